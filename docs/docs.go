@@ -1,5 +1,4 @@
-// Package docs GENERATED BY SWAG; DO NOT EDIT
-// This file was generated by swaggo/swag
+// Package docs Code generated by swaggo/swag. DO NOT EDIT
 package docs
 
 import "github.com/swaggo/swag"
@@ -21,9 +20,65 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/openai": {
+        "/admin/budgets": {
+            "get": {
+                "description": "Retorna gasto, limite e avisos dos tetos diário e mensal globais e, se informado, de um chamador",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Situação dos tetos de gasto com o LLM",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Chave de administração (ADMIN_API_KEY)",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Chamador (nome em API_KEYS ou anonymous)",
+                        "name": "caller",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/entities.BudgetStatus"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Chave de administração inválida",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/budgets/raise": {
             "post": {
-                "description": "Recebe um prompt e retorna uma resposta gerada pelo modelo OpenAI",
+                "description": "Soma amount_usd ao limite diário ou mensal do escopo (global ou nome do chamador) no período corrente, por duration ou até o fim do período. Um novo aumento substitui o anterior",
                 "consumes": [
                     "application/json"
                 ],
@@ -31,32 +86,128 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "OpenAI"
+                    "Admin"
                 ],
-                "summary": "Gera uma resposta da OpenAI",
+                "summary": "Aumenta temporariamente um teto de gasto",
                 "parameters": [
                     {
-                        "description": "Prompt para a OpenAI",
+                        "type": "string",
+                        "description": "Chave de administração (ADMIN_API_KEY)",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Escopo, período, valor e duração do aumento",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/entities.OpenAIRequest"
+                            "$ref": "#/definitions/entities.BudgetRaiseRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Resposta gerada",
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.BudgetStatus"
+                        }
+                    },
+                    "400": {
+                        "description": "Erro de validação",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Chave de administração inválida",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
                                 "type": "string"
                             }
                         }
+                    }
+                }
+            }
+        },
+        "/audit": {
+            "get": {
+                "description": "Retorna os eventos de requisições e jobs no período, em ordem cronológica, filtrados por chamador e status (valor exato, ex.: 402 ou completed, ou classe HTTP, ex.: 5xx). Use next como cursor para a página seguinte",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Consulta o log de auditoria",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Chave de administração (ADMIN_API_KEY)",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Início do período (RFC 3339 ou YYYY-MM-DD, padrão: 24 horas antes de to)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Fim do período (RFC 3339 ou YYYY-MM-DD, inclusive; padrão: agora)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Chamador (nome em API_KEYS ou anonymous)",
+                        "name": "caller",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Status HTTP, classe (4xx, 5xx) ou status final do job",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Máximo de eventos por página (padrão: 100, máximo: 1000)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor next devolvido pela página anterior",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.AuditPage"
+                        }
                     },
                     "400": {
-                        "description": "Erro de validação",
+                        "description": "Parâmetros inválidos",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Chave de administração inválida",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -65,7 +216,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "Erro interno",
+                        "description": "Internal server error",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -76,9 +227,30 @@ const docTemplate = `{
                 }
             }
         },
-        "/process-pdf": {
+        "/health": {
+            "get": {
+                "description": "Retorna ok quando o Redis responde; degraded indica que a API está no ar, mas jobs, auditoria e cache estão indisponíveis",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Health"
+                ],
+                "summary": "Verifica a saúde da aplicação",
+                "responses": {
+                    "200": {
+                        "description": "status (ok ou degraded) e redis",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs": {
             "post": {
-                "description": "Recebe um arquivo PDF e processa cada página, retornando os resultados",
+                "description": "Recebe um arquivo PDF e devolve imediatamente o ID do job; o progresso é consultado em GET /jobs/{id}",
                 "consumes": [
                     "multipart/form-data"
                 ],
@@ -86,9 +258,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "PDF"
+                    "Jobs"
                 ],
-                "summary": "Processa um arquivo PDF",
+                "summary": "Cria um job assíncrono de processamento de PDF",
                 "parameters": [
                     {
                         "type": "file",
@@ -96,21 +268,229 @@ const docTemplate = `{
                         "name": "file",
                         "in": "formData",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Chave que torna repetições da requisição seguras: a resposta da primeira é devolvida sem reprocessar",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "enum": [
+                            "ocr",
+                            "vision"
+                        ],
+                        "type": "string",
+                        "description": "Modo de extração: ocr (Tesseract + LLM) ou vision (somente LLM com visão)",
+                        "name": "mode",
+                        "in": "formData"
+                    },
+                    {
+                        "enum": [
+                            "tesseract",
+                            "vision",
+                            "fake"
+                        ],
+                        "type": "string",
+                        "description": "Backend de OCR usado no modo ocr (padrão: OCR_ENGINE)",
+                        "name": "ocr_engine",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Idiomas do OCR separados por + (ex.: por+eng)",
+                        "name": "ocr_lang",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page segmentation mode do Tesseract (padrão: 6)",
+                        "name": "ocr_psm",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "OCR engine mode do Tesseract, de 0 (legado) a 3; vazio usa o padrão",
+                        "name": "ocr_oem",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Resolução informada ao Tesseract",
+                        "name": "ocr_dpi",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Inclui o texto reconhecido pelo OCR no resultado de cada página",
+                        "name": "include_ocr_text",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Nome do esquema de extração registrado (ver GET /schemas); none desativa o padrão",
+                        "name": "schema",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Definição de esquema em JSON (name, description, fields), usada no lugar de schema",
+                        "name": "schema_definition",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Ignora o cache de resultados e reprocessa todas as páginas (1 ou true)",
+                        "name": "nocache",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/entities.Job"
+                        }
+                    },
+                    "400": {
+                        "description": "Failed to receive the file",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "X-API-Key fora de API_KEYS",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "402": {
+                        "description": "Limite de gasto com o LLM atingido",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Requisição com a mesma Idempotency-Key em andamento",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key reutilizada com outra query string ou outro corpo",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Limite de requisições atingido (ver Retry-After e RateLimit-*)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs/{id}": {
+            "get": {
+                "description": "Retorna o status, o progresso por página e os resultados parciais do job",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Jobs"
+                ],
+                "summary": "Consulta um job de processamento de PDF",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID do job",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "type": "object",
-                                "additionalProperties": true
+                            "$ref": "#/definitions/entities.Job"
+                        }
+                    },
+                    "404": {
+                        "description": "Job não encontrado",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
                             }
                         }
                     },
-                    "400": {
-                        "description": "Failed to receive the file",
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Interrompe o processamento; os resultados das páginas já concluídas são mantidos",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Jobs"
+                ],
+                "summary": "Cancela um job de processamento de PDF",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID do job",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.Job"
+                        }
+                    },
+                    "404": {
+                        "description": "Job não encontrado",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -129,14 +509,1051 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "entities.OpenAIRequest": {
-            "type": "object",
-            "properties": {
-                "prompt": {
-                    "type": "string"
+        },
+        "/openai": {
+            "post": {
+                "description": "Recebe um prompt e retorna uma resposta gerada pelo modelo OpenAI",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "OpenAI"
+                ],
+                "summary": "Gera uma resposta da OpenAI",
+                "parameters": [
+                    {
+                        "description": "Prompt para a OpenAI",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entities.OpenAIRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Chave que torna repetições da requisição seguras: a resposta da primeira é devolvida sem reprocessar",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Resposta gerada, consumo de tokens (usage) e avisos de orçamento (warnings)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Erro de validação",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "X-API-Key fora de API_KEYS",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "402": {
+                        "description": "Limite de gasto com o LLM atingido",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Requisição com a mesma Idempotency-Key em andamento",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Prompt excede o orçamento de tokens",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key reutilizada com outra query string ou outro corpo",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Limite de requisições atingido (ver Retry-After e RateLimit-*)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Erro interno",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/process-pdf": {
+            "post": {
+                "description": "Recebe um arquivo PDF e processa cada página, retornando os resultados por página e a lista de produtos consolidada entre as páginas. Páginas com camada de texto nativa dispensam OCR; o campo method indica o caminho usado (text_layer, ocr ou vision). O texto do OCR e a extração de páginas já vistas são reaproveitados do cache; o campo cache indica hit ou miss por página",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "PDF"
+                ],
+                "summary": "Processa um arquivo PDF",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "PDF file to be processed",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Chave que torna repetições da requisição seguras: a resposta da primeira é devolvida sem reprocessar",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "enum": [
+                            "ocr",
+                            "vision"
+                        ],
+                        "type": "string",
+                        "description": "Modo de extração: ocr (Tesseract + LLM) ou vision (somente LLM com visão)",
+                        "name": "mode",
+                        "in": "formData"
+                    },
+                    {
+                        "enum": [
+                            "tesseract",
+                            "vision",
+                            "fake"
+                        ],
+                        "type": "string",
+                        "description": "Backend de OCR usado no modo ocr (padrão: OCR_ENGINE)",
+                        "name": "ocr_engine",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Idiomas do OCR separados por + (ex.: por+eng)",
+                        "name": "ocr_lang",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page segmentation mode do Tesseract (padrão: 6)",
+                        "name": "ocr_psm",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "OCR engine mode do Tesseract, de 0 (legado) a 3; vazio usa o padrão",
+                        "name": "ocr_oem",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Resolução informada ao Tesseract",
+                        "name": "ocr_dpi",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Inclui o texto reconhecido pelo OCR no resultado de cada página",
+                        "name": "include_ocr_text",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Nome do esquema de extração registrado (ver GET /schemas); none desativa o padrão",
+                        "name": "schema",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Definição de esquema em JSON (name, description, fields), usada no lugar de schema",
+                        "name": "schema_definition",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Ignora o cache de resultados e reprocessa todas as páginas (1 ou true)",
+                        "name": "nocache",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.DocumentResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Failed to receive the file",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "X-API-Key fora de API_KEYS",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "402": {
+                        "description": "Limite de gasto com o LLM atingido",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Requisição com a mesma Idempotency-Key em andamento",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key reutilizada com outra query string ou outro corpo",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Limite de requisições atingido (ver Retry-After e RateLimit-*)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "504": {
+                        "description": "Tempo de espera esgotado; o job continua em /jobs/{id} (cabeçalho Location), e a resposta é repetida para a mesma Idempotency-Key",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/queue": {
+            "get": {
+                "description": "Retorna quantas páginas aguardam processamento, quantas estão com os workers e quantos jobs estão ativos",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Jobs"
+                ],
+                "summary": "Consulta a fila de páginas",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.QueueStats"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/schemas": {
+            "get": {
+                "description": "Retorna os esquemas de extração registrados, que podem ser escolhidos pelo campo schema de /process-pdf e /jobs",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "PDF"
+                ],
+                "summary": "Lista os esquemas de extração",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/schema.Schema"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/usage": {
+            "get": {
+                "description": "Retorna chamadas, tokens e custo estimado por dia e por chamador no período (datas em UTC, inclusive). Com format=csv, devolve o relatório como arquivo CSV",
+                "produces": [
+                    "application/json",
+                    "text/csv"
+                ],
+                "tags": [
+                    "Usage"
+                ],
+                "summary": "Relatório de consumo do LLM",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Chave de administração (ADMIN_API_KEY)",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Data inicial (YYYY-MM-DD, padrão: 30 dias antes de to)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Data final (YYYY-MM-DD, padrão: hoje)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filtra por chamador (nome em API_KEYS ou anonymous)",
+                        "name": "caller",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "csv"
+                        ],
+                        "type": "string",
+                        "description": "Formato da resposta",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.UsageReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Parâmetros inválidos",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Chave de administração inválida",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/usage/documents/{id}": {
+            "get": {
+                "description": "Retorna chamadas, tokens e custo estimado acumulados no processamento de um documento (ID do job)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Usage"
+                ],
+                "summary": "Consumo do LLM de um documento",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Chave de administração (ADMIN_API_KEY)",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID do documento",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.DocumentUsage"
+                        }
+                    },
+                    "401": {
+                        "description": "Chave de administração inválida",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Nenhum consumo registrado",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "entities.AuditEvent": {
+            "type": "object",
+            "properties": {
+                "caller": {
+                    "type": "string"
+                },
+                "completion_tokens": {
+                    "type": "integer"
+                },
+                "detail": {
+                    "type": "string"
+                },
+                "document_hash": {
+                    "type": "string"
+                },
+                "document_id": {
+                    "type": "string"
+                },
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "method": {
+                    "type": "string"
+                },
+                "model": {
+                    "type": "string"
+                },
+                "prompt_tokens": {
+                    "type": "integer"
+                },
+                "request_id": {
+                    "type": "string"
+                },
+                "route": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "time": {
+                    "type": "string"
+                },
+                "total_tokens": {
+                    "type": "integer"
+                }
+            }
+        },
+        "entities.AuditPage": {
+            "type": "object",
+            "properties": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.AuditEvent"
+                    }
+                },
+                "next": {
+                    "type": "string"
+                }
+            }
+        },
+        "entities.BudgetRaiseRequest": {
+            "type": "object",
+            "properties": {
+                "amount_usd": {
+                    "type": "number"
+                },
+                "duration": {
+                    "description": "Duration no formato de time.ParseDuration (ex.: \"4h\"); vazio vale até o fim do período.",
+                    "type": "string"
+                },
+                "period": {
+                    "type": "string"
+                },
+                "scope": {
+                    "type": "string"
+                }
+            }
+        },
+        "entities.BudgetStatus": {
+            "type": "object",
+            "properties": {
+                "base_limit_usd": {
+                    "type": "number"
+                },
+                "exceeded": {
+                    "type": "boolean"
+                },
+                "limit_usd": {
+                    "type": "number"
+                },
+                "period": {
+                    "type": "string"
+                },
+                "period_id": {
+                    "type": "string"
+                },
+                "raise_expires_at": {
+                    "type": "string"
+                },
+                "raise_usd": {
+                    "type": "number"
+                },
+                "scope": {
+                    "type": "string"
+                },
+                "spent_usd": {
+                    "type": "number"
+                },
+                "warning": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "entities.DocumentResult": {
+            "type": "object",
+            "properties": {
+                "document_id": {
+                    "type": "string"
+                },
+                "file_sha256": {
+                    "type": "string"
+                },
+                "products": {
+                    "$ref": "#/definitions/entities.ProductList"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.PageResult"
+                    }
+                },
+                "usage": {
+                    "$ref": "#/definitions/entities.UsageSummary"
+                }
+            }
+        },
+        "entities.DocumentUsage": {
+            "type": "object",
+            "properties": {
+                "caller": {
+                    "type": "string"
+                },
+                "calls": {
+                    "type": "integer"
+                },
+                "completion_tokens": {
+                    "type": "integer"
+                },
+                "cost_usd": {
+                    "type": "number"
+                },
+                "document_id": {
+                    "type": "string"
+                },
+                "prompt_tokens": {
+                    "type": "integer"
+                },
+                "total_tokens": {
+                    "type": "integer"
+                }
+            }
+        },
+        "entities.Job": {
+            "type": "object",
+            "properties": {
+                "caller": {
+                    "type": "string"
+                },
+                "completed_pages": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "failed_pages": {
+                    "type": "integer"
+                },
+                "file_sha256": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "mode": {
+                    "type": "string"
+                },
+                "ocr_engine": {
+                    "type": "string"
+                },
+                "products": {
+                    "$ref": "#/definitions/entities.ProductList"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.PageResult"
+                    }
+                },
+                "status": {
+                    "$ref": "#/definitions/entities.JobStatus"
+                },
+                "total_pages": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "usage": {
+                    "$ref": "#/definitions/entities.UsageSummary"
+                }
+            }
+        },
+        "entities.JobStatus": {
+            "type": "string",
+            "enum": [
+                "queued",
+                "running",
+                "completed",
+                "failed",
+                "canceled"
+            ],
+            "x-enum-varnames": [
+                "JobStatusQueued",
+                "JobStatusRunning",
+                "JobStatusCompleted",
+                "JobStatusFailed",
+                "JobStatusCanceled"
+            ]
+        },
+        "entities.OpenAIRequest": {
+            "type": "object",
+            "properties": {
+                "prompt": {
+                    "type": "string"
+                }
+            }
+        },
+        "entities.PageCache": {
+            "type": "object",
+            "properties": {
+                "extraction": {
+                    "type": "string"
+                },
+                "ocr": {
+                    "type": "string"
+                }
+            }
+        },
+        "entities.PageResult": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "cache": {
+                    "$ref": "#/definitions/entities.PageCache"
+                },
+                "chunks": {
+                    "type": "integer"
+                },
+                "complete": {
+                    "description": "Complete é false quando a resposta do modelo foi cortada pelo limite de tokens e os dados podem estar incompletos.",
+                    "type": "boolean"
+                },
+                "continuations": {
+                    "type": "integer"
+                },
+                "data": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "document_id": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "json_repairs": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "method": {
+                    "type": "string"
+                },
+                "model": {
+                    "type": "string"
+                },
+                "ocr_engine": {
+                    "type": "string"
+                },
+                "ocr_text": {
+                    "type": "string"
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "schema": {
+                    "type": "string"
+                },
+                "schema_errors": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "status": {
+                    "$ref": "#/definitions/entities.PageStatus"
+                },
+                "timings": {
+                    "$ref": "#/definitions/entities.PageTimings"
+                },
+                "usage": {
+                    "$ref": "#/definitions/entities.TokenUsage"
+                },
+                "warnings": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "entities.PageStatus": {
+            "type": "string",
+            "enum": [
+                "success",
+                "failed"
+            ],
+            "x-enum-varnames": [
+                "PageStatusSuccess",
+                "PageStatusFailed"
+            ]
+        },
+        "entities.PageTimings": {
+            "type": "object",
+            "properties": {
+                "llm_ms": {
+                    "type": "integer"
+                },
+                "ocr_ms": {
+                    "type": "integer"
+                },
+                "total_ms": {
+                    "type": "integer"
+                }
+            }
+        },
+        "entities.Product": {
+            "type": "object",
+            "properties": {
+                "fields": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "pages": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "entities.ProductList": {
+            "type": "object",
+            "properties": {
+                "columns": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "merged_rows": {
+                    "type": "integer"
+                },
+                "products": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.Product"
+                    }
+                },
+                "removed_duplicates": {
+                    "type": "integer"
+                },
+                "removed_headers": {
+                    "type": "integer"
+                }
+            }
+        },
+        "entities.TokenUsage": {
+            "type": "object",
+            "properties": {
+                "completion_tokens": {
+                    "type": "integer"
+                },
+                "estimated_prompt_tokens": {
+                    "description": "EstimatedPromptTokens é a estimativa local feita antes do envio, para comparação com PromptTokens.",
+                    "type": "integer"
+                },
+                "prompt_tokens": {
+                    "type": "integer"
+                },
+                "total_tokens": {
+                    "type": "integer"
+                }
+            }
+        },
+        "entities.UsageReport": {
+            "type": "object",
+            "properties": {
+                "caller": {
+                    "type": "string"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "rows": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.UsageRow"
+                    }
+                },
+                "to": {
+                    "type": "string"
+                },
+                "total": {
+                    "$ref": "#/definitions/entities.UsageSummary"
+                }
+            }
+        },
+        "entities.UsageRow": {
+            "type": "object",
+            "properties": {
+                "caller": {
+                    "type": "string"
+                },
+                "calls": {
+                    "type": "integer"
+                },
+                "completion_tokens": {
+                    "type": "integer"
+                },
+                "cost_usd": {
+                    "type": "number"
+                },
+                "date": {
+                    "type": "string"
+                },
+                "prompt_tokens": {
+                    "type": "integer"
+                },
+                "total_tokens": {
+                    "type": "integer"
+                }
+            }
+        },
+        "entities.UsageSummary": {
+            "type": "object",
+            "properties": {
+                "calls": {
+                    "type": "integer"
+                },
+                "completion_tokens": {
+                    "type": "integer"
+                },
+                "cost_usd": {
+                    "type": "number"
+                },
+                "prompt_tokens": {
+                    "type": "integer"
+                },
+                "total_tokens": {
+                    "type": "integer"
+                }
+            }
+        },
+        "schema.Field": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "enum": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "fields": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/schema.Field"
+                    }
+                },
+                "items": {
+                    "$ref": "#/definitions/schema.Field"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "required": {
+                    "type": "boolean"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "schema.Schema": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "fields": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/schema.Field"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "services.QueueStats": {
+            "type": "object",
+            "properties": {
+                "active_jobs": {
+                    "type": "integer"
+                },
+                "in_progress": {
+                    "type": "integer"
+                },
+                "waiting": {
+                    "type": "integer"
                 }
             }
         }
@@ -153,6 +1570,8 @@ var SwaggerInfo = &swag.Spec{
 	Description:      "API para o sistema GoSmart com integração OpenAI e suporte a logs",
 	InfoInstanceName: "swagger",
 	SwaggerTemplate:  docTemplate,
+	LeftDelim:        "{{",
+	RightDelim:       "}}",
 }
 
 func init() {
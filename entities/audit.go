@@ -0,0 +1,36 @@
+package entities
+
+import "time"
+
+const (
+	AuditKindRequest = "request"
+	AuditKindJob     = "job"
+)
+
+// AuditEvent é um registro do log de auditoria. Eventos de requisição trazem o status HTTP;
+// eventos de job são gravados ao fim do processamento assíncrono, com o status final do job.
+type AuditEvent struct {
+	ID               string    `json:"id,omitempty"`
+	Time             time.Time `json:"time"`
+	Kind             string    `json:"kind"`
+	RequestID        string    `json:"request_id,omitempty"`
+	Method           string    `json:"method,omitempty"`
+	Route            string    `json:"route,omitempty"`
+	Caller           string    `json:"caller"`
+	DocumentID       string    `json:"document_id,omitempty"`
+	DocumentHash     string    `json:"document_hash,omitempty"`
+	Model            string    `json:"model,omitempty"`
+	PromptTokens     int64     `json:"prompt_tokens,omitempty"`
+	CompletionTokens int64     `json:"completion_tokens,omitempty"`
+	TotalTokens      int64     `json:"total_tokens,omitempty"`
+	Status           string    `json:"status"`
+	DurationMs       int64     `json:"duration_ms"`
+	Error            string    `json:"error,omitempty"`
+	Detail           string    `json:"detail,omitempty"`
+}
+
+// AuditPage é a resposta de GET /audit. Next, quando presente, é o cursor da página seguinte.
+type AuditPage struct {
+	Events []AuditEvent `json:"events"`
+	Next   string       `json:"next,omitempty"`
+}
@@ -0,0 +1,27 @@
+package entities
+
+import "time"
+
+// BudgetStatus é a situação de um teto de gasto com o LLM no período corrente.
+// Scope é "global" ou o nome do chamador; LimitUSD já inclui o aumento temporário (RaiseUSD).
+type BudgetStatus struct {
+	Scope          string     `json:"scope"`
+	Period         string     `json:"period"`
+	PeriodID       string     `json:"period_id"`
+	SpentUSD       float64    `json:"spent_usd"`
+	LimitUSD       float64    `json:"limit_usd"`
+	BaseLimitUSD   float64    `json:"base_limit_usd"`
+	RaiseUSD       float64    `json:"raise_usd,omitempty"`
+	RaiseExpiresAt *time.Time `json:"raise_expires_at,omitempty"`
+	Warning        bool       `json:"warning"`
+	Exceeded       bool       `json:"exceeded"`
+}
+
+// BudgetRaiseRequest é o corpo de POST /admin/budgets/raise.
+type BudgetRaiseRequest struct {
+	Scope     string  `json:"scope"`
+	Period    string  `json:"period"`
+	AmountUSD float64 `json:"amount_usd"`
+	// Duration no formato de time.ParseDuration (ex.: "4h"); vazio vale até o fim do período.
+	Duration string `json:"duration,omitempty"`
+}
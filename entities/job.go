@@ -0,0 +1,40 @@
+package entities
+
+import "time"
+
+type JobStatus string
+
+const (
+	JobStatusQueued    JobStatus = "queued"
+	JobStatusRunning   JobStatus = "running"
+	JobStatusCompleted JobStatus = "completed"
+	JobStatusFailed    JobStatus = "failed"
+	JobStatusCanceled  JobStatus = "canceled"
+)
+
+// Job representa o processamento assíncrono de um PDF enviado para POST /jobs.
+// Results acompanha a ordem das páginas; páginas ainda não processadas ficam como null.
+// Products traz a lista consolidada de produtos quando o job é concluído.
+// Caller identifica quem enviou o PDF e FileHash é o SHA-256 do arquivo; Usage é o consumo acumulado do LLM no documento.
+type Job struct {
+	ID             string        `json:"id"`
+	Caller         string        `json:"caller,omitempty"`
+	FileHash       string        `json:"file_sha256,omitempty"`
+	Status         JobStatus     `json:"status"`
+	Mode           string        `json:"mode"`
+	OCREngine      string        `json:"ocr_engine,omitempty"`
+	TotalPages     int           `json:"total_pages"`
+	CompletedPages int           `json:"completed_pages"`
+	FailedPages    int           `json:"failed_pages"`
+	Results        []*PageResult `json:"results"`
+	Products       *ProductList  `json:"products,omitempty"`
+	Usage          *UsageSummary `json:"usage,omitempty"`
+	Error          string        `json:"error,omitempty"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+}
+
+// Finished indica se o job chegou a um estado final.
+func (j *Job) Finished() bool {
+	return j.Status == JobStatusCompleted || j.Status == JobStatusFailed || j.Status == JobStatusCanceled
+}
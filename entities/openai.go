@@ -1,27 +1,91 @@
 package entities
 
+import "encoding/json"
+
 type OpenAIRequest struct {
 	Prompt string `json:"prompt"`
 }
 
 type ChatCompletionRequest struct {
-	Model       string        `json:"model"`
-	Messages    []ChatMessage `json:"messages"`
-	MaxTokens   int           `json:"max_tokens,omitempty"`
-	Temperature float32       `json:"temperature,omitempty"`
+	Model     string                  `json:"model"`
+	Messages  []ChatCompletionMessage `json:"messages"`
+	MaxTokens int                     `json:"max_tokens,omitempty"`
+	// Temperature é ponteiro para que 0 seja enviado; nil usa o padrão da API.
+	Temperature    *float64        `json:"temperature,omitempty"`
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+	Tools          []Tool          `json:"tools,omitempty"`
+	ToolChoice     *ToolChoice     `json:"tool_choice,omitempty"`
+}
+
+// ResponseFormat restringe a resposta do modelo; com Type "json_schema" a saída segue JSONSchema.
+type ResponseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *JSONSchemaFormat `json:"json_schema,omitempty"`
+}
+
+type JSONSchemaFormat struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Schema      map[string]interface{} `json:"schema"`
+	Strict      bool                   `json:"strict"`
+}
+
+// Tool descreve uma função que o modelo pode chamar; Parameters é um JSON Schema.
+type Tool struct {
+	Type     string       `json:"type"`
+	Function ToolFunction `json:"function"`
+}
+
+type ToolFunction struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters"`
 }
 
-type ChatMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+// ToolChoice obriga o modelo a chamar a função indicada.
+type ToolChoice struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name string `json:"name"`
+	} `json:"function"`
+}
+
+type ToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
 }
 
 type ChatCompletionResponse struct {
 	Choices []struct {
 		Message struct {
-			Content string `json:"content"`
+			Content   string     `json:"content"`
+			ToolCalls []ToolCall `json:"tool_calls,omitempty"`
 		} `json:"message"`
+		FinishReason string `json:"finish_reason"`
 	} `json:"choices"`
+	Usage    TokenUsage `json:"usage"`
+	Attempts int        `json:"-"`
+}
+
+// TokenUsage é o consumo de tokens informado pela API em cada chamada.
+type TokenUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+	// EstimatedPromptTokens é a estimativa local feita antes do envio, para comparação com PromptTokens.
+	EstimatedPromptTokens int `json:"estimated_prompt_tokens,omitempty"`
+}
+
+// Add soma o consumo de outra chamada.
+func (u *TokenUsage) Add(other TokenUsage) {
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.TotalTokens += other.TotalTokens
+	u.EstimatedPromptTokens += other.EstimatedPromptTokens
 }
 
 type OpenAIModel struct {
@@ -33,14 +97,54 @@ type OpenAIModel struct {
 	} `json:"permission"`
 }
 
+// ChatCompletionMessage representa uma mensagem do chat. Quando Parts está preenchido,
+// o conteúdo é enviado como lista de partes (texto e imagens) em vez de texto simples.
 type ChatCompletionMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role    string        `json:"role"`
+	Content string        `json:"-"`
+	Parts   []ContentPart `json:"-"`
 }
 
-type ParsePdfRequest struct {
-	Model       string                  `json:"model"`
-	Messages    []ChatCompletionMessage `json:"messages"`
-	MaxTokens   int                     `json:"max_tokens,omitempty"`
-	Temperature float64                 `json:"temperature,omitempty"`
+type ContentPart struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	ImageURL *ImageURL `json:"image_url,omitempty"`
+}
+
+type ImageURL struct {
+	URL    string `json:"url"`
+	Detail string `json:"detail,omitempty"`
+}
+
+func (m ChatCompletionMessage) MarshalJSON() ([]byte, error) {
+	type message struct {
+		Role    string      `json:"role"`
+		Content interface{} `json:"content"`
+	}
+
+	if len(m.Parts) > 0 {
+		return json.Marshal(message{Role: m.Role, Content: m.Parts})
+	}
+	return json.Marshal(message{Role: m.Role, Content: m.Content})
+}
+
+func (m *ChatCompletionMessage) UnmarshalJSON(data []byte) error {
+	var message struct {
+		Role    string          `json:"role"`
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(data, &message); err != nil {
+		return err
+	}
+
+	m.Role = message.Role
+	m.Content = ""
+	m.Parts = nil
+	if len(message.Content) == 0 || string(message.Content) == "null" {
+		return nil
+	}
+	if message.Content[0] == '[' {
+		return json.Unmarshal(message.Content, &m.Parts)
+	}
+	return json.Unmarshal(message.Content, &m.Content)
 }
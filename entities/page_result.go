@@ -0,0 +1,59 @@
+package entities
+
+type PageStatus string
+
+const (
+	PageStatusSuccess PageStatus = "success"
+	PageStatusFailed  PageStatus = "failed"
+)
+
+const (
+	CacheHit    = "hit"
+	CacheMiss   = "miss"
+	CacheBypass = "bypass"
+)
+
+// PageCache indica se o texto do OCR e a extração da página vieram do cache de resultados.
+// Bypass significa que a requisição pediu nocache: o cache não foi consultado, mas foi atualizado.
+type PageCache struct {
+	OCR        string `json:"ocr,omitempty"`
+	Extraction string `json:"extraction,omitempty"`
+}
+
+// PageTimings registra a duração, em milissegundos, de cada etapa do processamento da página.
+type PageTimings struct {
+	OCRMs   int64 `json:"ocr_ms,omitempty"`
+	LLMMs   int64 `json:"llm_ms,omitempty"`
+	TotalMs int64 `json:"total_ms"`
+}
+
+// PageResult é o resultado do processamento de uma página do PDF.
+// Status indica o desfecho; em caso de falha, Error descreve o motivo e Data fica vazio.
+type PageResult struct {
+	DocumentID   string      `json:"document_id"`
+	Page         int         `json:"page"`
+	Status       PageStatus  `json:"status"`
+	Method       string      `json:"method,omitempty"`
+	OCREngine    string      `json:"ocr_engine,omitempty"`
+	OCRText      string      `json:"ocr_text,omitempty"`
+	Model        string      `json:"model,omitempty"`
+	Attempts     int         `json:"attempts,omitempty"`
+	Usage        *TokenUsage `json:"usage,omitempty"`
+	Schema       string      `json:"schema,omitempty"`
+	SchemaErrors []string    `json:"schema_errors,omitempty"`
+	JSONRepairs  []string    `json:"json_repairs,omitempty"`
+	// Complete é false quando a resposta do modelo foi cortada pelo limite de tokens e os dados podem estar incompletos.
+	Complete      bool                   `json:"complete"`
+	Chunks        int                    `json:"chunks,omitempty"`
+	Continuations int                    `json:"continuations,omitempty"`
+	Cache         *PageCache             `json:"cache,omitempty"`
+	Timings       PageTimings            `json:"timings"`
+	Warnings      []string               `json:"warnings,omitempty"`
+	Error         string                 `json:"error,omitempty"`
+	Data          map[string]interface{} `json:"data,omitempty"`
+}
+
+// Failed indica se a página terminou com erro.
+func (r *PageResult) Failed() bool {
+	return r.Status == PageStatusFailed
+}
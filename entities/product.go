@@ -0,0 +1,27 @@
+package entities
+
+// Product é uma linha da lista consolidada de produtos. Pages indica de quais páginas
+// a linha veio; uma linha quebrada entre páginas lista as duas.
+type Product struct {
+	Pages  []int                  `json:"pages"`
+	Fields map[string]interface{} `json:"fields"`
+}
+
+// ProductList é a lista de produtos do documento inteiro, montada a partir dos resultados das páginas.
+// Columns é o esquema de colunas detectado, na ordem em que foi encontrado no documento.
+type ProductList struct {
+	Columns           []string  `json:"columns"`
+	Products          []Product `json:"products"`
+	MergedRows        int       `json:"merged_rows"`
+	RemovedHeaders    int       `json:"removed_headers"`
+	RemovedDuplicates int       `json:"removed_duplicates"`
+}
+
+// DocumentResult é a resposta de /process-pdf: os resultados por página, a lista consolidada de produtos e o consumo do LLM.
+type DocumentResult struct {
+	DocumentID string        `json:"document_id"`
+	FileHash   string        `json:"file_sha256,omitempty"`
+	Results    []*PageResult `json:"results"`
+	Products   *ProductList  `json:"products,omitempty"`
+	Usage      *UsageSummary `json:"usage,omitempty"`
+}
@@ -0,0 +1,42 @@
+package entities
+
+// UsageSummary soma o consumo de chamadas ao LLM: número de chamadas, tokens e custo em dólares.
+type UsageSummary struct {
+	Calls            int64   `json:"calls"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	TotalTokens      int64   `json:"total_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+}
+
+// Add soma outro resumo a este.
+func (u *UsageSummary) Add(other UsageSummary) {
+	u.Calls += other.Calls
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.TotalTokens += other.TotalTokens
+	u.CostUSD += other.CostUSD
+}
+
+// UsageRow é o consumo de um chamador em um dia.
+type UsageRow struct {
+	Date   string `json:"date"`
+	Caller string `json:"caller"`
+	UsageSummary
+}
+
+// UsageReport é a resposta de GET /usage: o consumo por dia e chamador no período e o total.
+type UsageReport struct {
+	From   string       `json:"from"`
+	To     string       `json:"to"`
+	Caller string       `json:"caller,omitempty"`
+	Rows   []UsageRow   `json:"rows"`
+	Total  UsageSummary `json:"total"`
+}
+
+// DocumentUsage é o consumo acumulado no processamento de um documento.
+type DocumentUsage struct {
+	DocumentID string `json:"document_id"`
+	Caller     string `json:"caller,omitempty"`
+	UsageSummary
+}
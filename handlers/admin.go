@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"gosmart/entities"
+	"gosmart/services"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequireAdmin protege as rotas /admin, /usage e /audit com a chave de ADMIN_API_KEY, enviada no cabeçalho X-Admin-Key.
+func RequireAdmin(c *fiber.Ctx) error {
+	if !services.AdminEnabled() {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Administração desativada: configure ADMIN_API_KEY"})
+	}
+	if !services.IsAdmin(c.Get("X-Admin-Key")) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Chave de administração inválida"})
+	}
+	return c.Next()
+}
+
+// BudgetStatusHandler godoc
+// @Summary Situação dos tetos de gasto com o LLM
+// @Description Retorna gasto, limite e avisos dos tetos diário e mensal globais e, se informado, de um chamador
+// @Tags Admin
+// @Produce json
+// @Param X-Admin-Key header string true "Chave de administração (ADMIN_API_KEY)"
+// @Param caller query string false "Chamador (nome em API_KEYS ou anonymous)"
+// @Success 200 {array} entities.BudgetStatus
+// @Failure 401 {object} map[string]string "Chave de administração inválida"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /admin/budgets [get]
+func BudgetStatusHandler(c *fiber.Ctx) error {
+	statuses, err := services.Budgets.Status(c.UserContext(), c.Query("caller"))
+	if err != nil {
+		log.Printf("Erro ao consultar orçamento: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Internal Server Error"})
+	}
+
+	return c.JSON(statuses)
+}
+
+// RaiseBudgetHandler godoc
+// @Summary Aumenta temporariamente um teto de gasto
+// @Description Soma amount_usd ao limite diário ou mensal do escopo (global ou nome do chamador) no período corrente, por duration ou até o fim do período. Um novo aumento substitui o anterior
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Chave de administração (ADMIN_API_KEY)"
+// @Param request body entities.BudgetRaiseRequest true "Escopo, período, valor e duração do aumento"
+// @Success 200 {object} entities.BudgetStatus
+// @Failure 400 {object} map[string]string "Erro de validação"
+// @Failure 401 {object} map[string]string "Chave de administração inválida"
+// @Router /admin/budgets/raise [post]
+func RaiseBudgetHandler(c *fiber.Ctx) error {
+	var req entities.BudgetRaiseRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	if req.Scope == "" {
+		req.Scope = services.BudgetScopeGlobal
+	}
+
+	var duration time.Duration
+	if req.Duration != "" {
+		parsed, err := time.ParseDuration(req.Duration)
+		if err != nil || parsed <= 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Duração inválida"})
+		}
+		duration = parsed
+	}
+
+	status, err := services.Budgets.Raise(c.UserContext(), req.Scope, req.Period, req.AmountUSD, duration)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(status)
+}
@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"gosmart/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequireAPIKey recusa com 401 as requisições com X-API-Key fora de API_KEYS, quando configurada.
+// Sem API_KEYS, ou sem o cabeçalho, a requisição segue como AnonymousCaller.
+func RequireAPIKey(c *fiber.Ctx) error {
+	key := c.Get("X-API-Key")
+	if key != "" && services.APIKeysEnabled() && !services.KnownAPIKey(key) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Chave de API inválida"})
+	}
+	return c.Next()
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"gosmart/services"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRequireAPIKey(t *testing.T) {
+	t.Setenv("API_KEYS", "erp=chave-erp")
+	services.InitCallers()
+
+	app := fiber.New()
+	app.Get("/", RequireAPIKey, func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+
+	tests := []struct {
+		name   string
+		apiKey string
+		want   int
+	}{
+		{name: "chave configurada", apiKey: "chave-erp", want: fiber.StatusOK},
+		{name: "sem chave", apiKey: "", want: fiber.StatusOK},
+		{name: "chave desconhecida", apiKey: "aleatoria", want: fiber.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(fiber.MethodGet, "/", nil)
+			if tt.apiKey != "" {
+				request.Header.Set("X-API-Key", tt.apiKey)
+			}
+			response, err := app.Test(request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if response.StatusCode != tt.want {
+				t.Errorf("status = %d, esperado %d", response.StatusCode, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"errors"
+	"gosmart/entities"
+	"gosmart/services"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// AuditRequests grava um evento de auditoria por requisição, com o ID da requisição (X-Request-ID,
+// gerado quando ausente), a rota, o chamador, o status e a duração. Handlers e serviços completam o
+// evento com documento, modelo e tokens por meio de services.AnnotateAudit.
+func AuditRequests(c *fiber.Ctx) error {
+	if c.Method() == fiber.MethodOptions || c.Path() == "/health" || strings.HasPrefix(c.Path(), "/swagger") {
+		return c.Next()
+	}
+
+	started := time.Now()
+	requestID := c.Get("X-Request-ID")
+	if requestID == "" {
+		requestID = uuid.New().String()
+	}
+	c.Set("X-Request-ID", requestID)
+
+	event := &entities.AuditEvent{
+		Time:      started,
+		Kind:      entities.AuditKindRequest,
+		RequestID: requestID,
+		Method:    c.Method(),
+		Caller:    services.CallerID(c.Get("X-API-Key")),
+	}
+	ctx := services.WithAuditEvent(c.UserContext(), event)
+	c.SetUserContext(ctx)
+
+	err := c.Next()
+
+	status := c.Response().StatusCode()
+	if err != nil {
+		// O erro ainda será convertido em resposta pelo ErrorHandler do Fiber
+		status = fiber.StatusInternalServerError
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) {
+			status = fiberErr.Code
+		}
+	}
+
+	var final entities.AuditEvent
+	services.AnnotateAudit(ctx, func(event *entities.AuditEvent) {
+		event.Route = c.Route().Path
+		event.Status = strconv.Itoa(status)
+		event.DurationMs = time.Since(started).Milliseconds()
+		if err != nil && event.Error == "" {
+			event.Error = err.Error()
+		}
+		final = *event
+	})
+	services.Audit.Record(ctx, final)
+
+	return err
+}
+
+// AuditHandler godoc
+// @Summary Consulta o log de auditoria
+// @Description Retorna os eventos de requisições e jobs no período, em ordem cronológica, filtrados por chamador e status (valor exato, ex.: 402 ou completed, ou classe HTTP, ex.: 5xx). Use next como cursor para a página seguinte
+// @Tags Admin
+// @Produce json
+// @Param X-Admin-Key header string true "Chave de administração (ADMIN_API_KEY)"
+// @Param from query string false "Início do período (RFC 3339 ou YYYY-MM-DD, padrão: 24 horas antes de to)"
+// @Param to query string false "Fim do período (RFC 3339 ou YYYY-MM-DD, inclusive; padrão: agora)"
+// @Param caller query string false "Chamador (nome em API_KEYS ou anonymous)"
+// @Param status query string false "Status HTTP, classe (4xx, 5xx) ou status final do job"
+// @Param limit query int false "Máximo de eventos por página (padrão: 100, máximo: 1000)"
+// @Param cursor query string false "Cursor next devolvido pela página anterior"
+// @Success 200 {object} entities.AuditPage
+// @Failure 400 {object} map[string]string "Parâmetros inválidos"
+// @Failure 401 {object} map[string]string "Chave de administração inválida"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /audit [get]
+func AuditHandler(c *fiber.Ctx) error {
+	to := time.Now()
+	if value := c.Query("to"); value != "" {
+		parsed, err := parseAuditTime(value, true)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Data inválida em to"})
+		}
+		to = parsed
+	}
+	from := to.Add(-24 * time.Hour)
+	if value := c.Query("from"); value != "" {
+		parsed, err := parseAuditTime(value, false)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Data inválida em from"})
+		}
+		from = parsed
+	}
+	if from.After(to) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "from deve ser anterior ou igual a to"})
+	}
+
+	limit := c.QueryInt("limit", services.DefaultAuditQueryLimit)
+	if limit < 1 || limit > services.MaxAuditQueryLimit {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "limit deve estar entre 1 e " + strconv.Itoa(services.MaxAuditQueryLimit)})
+	}
+
+	page, err := services.Audit.Query(c.UserContext(), services.AuditQuery{
+		From:   from,
+		To:     to,
+		Caller: c.Query("caller"),
+		Status: c.Query("status"),
+		Limit:  limit,
+		After:  c.Query("cursor"),
+	})
+	if err != nil {
+		log.Printf("Erro ao consultar auditoria: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Internal Server Error"})
+	}
+
+	return c.JSON(page)
+}
+
+// parseAuditTime aceita RFC 3339 ou uma data; como fim do período, a data inclui o dia inteiro.
+func parseAuditTime(value string, end bool) (time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+	parsed, err := time.Parse(usageDateLayout, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if end {
+		parsed = parsed.Add(24*time.Hour - time.Millisecond)
+	}
+	return parsed, nil
+}
@@ -19,10 +19,9 @@ func ExampleHandler(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "Invalid Protobuf data"})
 	}
 
-	if err := services.LogToRedis("logg", req.Input); err != nil {
-		log.Println("Erro ao logar no Redis: ", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Internal Server Error"})
-	}
+	services.AnnotateAudit(c.UserContext(), func(event *entities.AuditEvent) {
+		event.Detail = req.Input
+	})
 
 	res := &entities.ExampleResponse{Output: "Processed: " + req.Input}
 
@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"gosmart/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// HealthHandler godoc
+// @Summary Verifica a saúde da aplicação
+// @Description Retorna ok quando o Redis responde; degraded indica que a API está no ar, mas jobs, auditoria e cache estão indisponíveis
+// @Tags Health
+// @Produce json
+// @Success 200 {object} map[string]interface{} "status (ok ou degraded) e redis"
+// @Router /health [get]
+func HealthHandler(c *fiber.Ctx) error {
+	status := "ok"
+	if !services.RedisAvailable() {
+		status = "degraded"
+	}
+	return c.JSON(fiber.Map{"status": status, "redis": services.RedisAvailable()})
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"gosmart/services"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const maxIdempotencyKeyLength = 255
+
+// Idempotency repete a resposta de requisições enviadas de novo com o mesmo cabeçalho Idempotency-Key,
+// sem reprocessá-las. A chave vale por chamador e rota; enquanto a primeira tentativa roda, as repetições
+// recebem 409, e reutilizar a chave com outra query string ou outro corpo resulta em 422. Respostas 429 e 5xx não são gravadas,
+// para que o cliente possa tentar de novo com a mesma chave, exceto as 5xx com Location: o trabalho continua no
+// endereço indicado (como o 504 de /process-pdf com o job_id), e repetir a requisição criaria outro job.
+func Idempotency(c *fiber.Ctx) error {
+	idempotencyKey := c.Get("Idempotency-Key")
+	if idempotencyKey == "" {
+		return c.Next()
+	}
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Idempotency-Key muito longa"})
+	}
+
+	bodyHash, err := requestHash(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Falha ao ler o corpo da requisição"})
+	}
+
+	ctx := c.UserContext()
+	key := services.CallerID(c.Get("X-API-Key")) + ":" + c.Method() + ":" + c.Path() + ":" + idempotencyKey
+	record, err := services.Idempotency.Begin(ctx, key, bodyHash)
+	switch {
+	case errors.Is(err, services.ErrIdempotencyInFlight):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrIdempotencyMismatch):
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": err.Error()})
+	case err != nil:
+		// Sem o Redis a requisição segue sem proteção contra repetição
+		log.Printf("Idempotency-Key ignorada: %v", err)
+		return c.Next()
+	case record != nil:
+		c.Set("Idempotent-Replayed", "true")
+		c.Set(fiber.HeaderContentType, record.ContentType)
+		if record.Location != "" {
+			c.Set(fiber.HeaderLocation, record.Location)
+		}
+		return c.Status(record.Status).Send(record.Body)
+	}
+
+	if err := c.Next(); err != nil {
+		services.Idempotency.Release(ctx, key)
+		return err
+	}
+
+	// 429 e 5xx são temporários: a chave é liberada para que a repetição seja processada
+	status := c.Response().StatusCode()
+	location := string(c.Response().Header.Peek(fiber.HeaderLocation))
+	if status == fiber.StatusTooManyRequests || (status >= fiber.StatusInternalServerError && location == "") {
+		services.Idempotency.Release(ctx, key)
+		return nil
+	}
+
+	err = services.Idempotency.Complete(ctx, key, services.IdempotencyRecord{
+		BodyHash:    bodyHash,
+		Status:      status,
+		ContentType: string(c.Response().Header.ContentType()),
+		Location:    location,
+		Body:        append([]byte(nil), c.Response().Body()...),
+		CreatedAt:   time.Now(),
+	})
+	if err != nil {
+		log.Printf("Erro ao gravar resposta idempotente: %v", err)
+		services.Idempotency.Release(ctx, key)
+	}
+	return nil
+}
+
+// requestHash resume a query string e o corpo da requisição. Em formulários multipart, o boundary muda
+// a cada envio, então o hash é feito sobre os campos e o conteúdo dos arquivos, em ordem.
+func requestHash(c *fiber.Ctx) (string, error) {
+	hash := sha256.New()
+	hash.Write([]byte("query\x00"))
+	hash.Write(c.Request().URI().QueryString())
+	hash.Write([]byte("\x00"))
+
+	if !strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+		hash.Write(c.Body())
+		return hex.EncodeToString(hash.Sum(nil)), nil
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		return "", err
+	}
+
+	names := make([]string, 0, len(form.Value))
+	for name := range form.Value {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range form.Value[name] {
+			hash.Write([]byte("field\x00" + name + "\x00" + value + "\x00"))
+		}
+	}
+
+	names = names[:0]
+	for name := range form.File {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, header := range form.File[name] {
+			hash.Write([]byte("file\x00" + name + "\x00"))
+			file, err := header.Open()
+			if err != nil {
+				return "", err
+			}
+			_, err = io.Copy(hash, file)
+			file.Close()
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"gosmart/services"
+	"io"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func TestRequestHashIncludesQueryString(t *testing.T) {
+	app := fiber.New()
+	app.Post("/process-pdf", func(c *fiber.Ctx) error {
+		hash, err := requestHash(c)
+		if err != nil {
+			return err
+		}
+		return c.SendString(hash)
+	})
+
+	hashOf := func(target string) string {
+		t.Helper()
+		request := httptest.NewRequest(fiber.MethodPost, target, strings.NewReader(`{"prompt":"x"}`))
+		request.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		response, err := app.Test(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(response.Body)
+		return string(body)
+	}
+
+	base := hashOf("/process-pdf?mode=ocr")
+	if again := hashOf("/process-pdf?mode=ocr"); again != base {
+		t.Errorf("mesma requisição gerou hashes diferentes: %s e %s", base, again)
+	}
+	if other := hashOf("/process-pdf?mode=vision"); other == base {
+		t.Error("query string diferente gerou o mesmo hash")
+	}
+}
+
+func TestIdempotencyReplaysResponseWithLocation(t *testing.T) {
+	if os.Getenv("REDIS_URL") == "" {
+		t.Setenv("REDIS_URL", "redis://localhost:6379/15")
+	}
+	t.Setenv("REDIS_CONNECT_RETRIES", "0")
+	t.Setenv("REDIS_HEALTH_INTERVAL", "0")
+	services.InitRedis()
+	if !services.RedisAvailable() {
+		t.Skip("Redis indisponível")
+	}
+	services.InitIdempotency()
+
+	var calls atomic.Int32
+	app := fiber.New()
+	app.Post("/timeout", Idempotency, func(c *fiber.Ctx) error {
+		calls.Add(1)
+		c.Set(fiber.HeaderLocation, "/jobs/1")
+		return c.Status(fiber.StatusGatewayTimeout).JSON(fiber.Map{"job_id": "1"})
+	})
+	app.Post("/error", Idempotency, func(c *fiber.Ctx) error {
+		calls.Add(1)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	})
+
+	tests := []struct {
+		path      string
+		wantCalls int32
+	}{
+		{path: "/timeout", wantCalls: 1},
+		{path: "/error", wantCalls: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			calls.Store(0)
+			key := t.Name() + "-" + uuid.New().String()
+			for i := 0; i < 2; i++ {
+				request := httptest.NewRequest(fiber.MethodPost, tt.path, strings.NewReader(`{}`))
+				request.Header.Set("Idempotency-Key", key)
+				response, err := app.Test(request)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if tt.wantCalls == 1 && response.Header.Get(fiber.HeaderLocation) != "/jobs/1" {
+					t.Errorf("tentativa %d sem Location", i+1)
+				}
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("handler chamado %d vezes, esperado %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
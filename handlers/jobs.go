@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"errors"
+	"gosmart/services"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// CreateJobHandler godoc
+// @Summary Cria um job assíncrono de processamento de PDF
+// @Description Recebe um arquivo PDF e devolve imediatamente o ID do job; o progresso é consultado em GET /jobs/{id}
+// @Tags Jobs
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "PDF file to be processed"
+// @Param Idempotency-Key header string false "Chave que torna repetições da requisição seguras: a resposta da primeira é devolvida sem reprocessar"
+// @Param mode formData string false "Modo de extração: ocr (Tesseract + LLM) ou vision (somente LLM com visão)" Enums(ocr, vision)
+// @Param ocr_engine formData string false "Backend de OCR usado no modo ocr (padrão: OCR_ENGINE)" Enums(tesseract, vision, fake)
+// @Param ocr_lang formData string false "Idiomas do OCR separados por + (ex.: por+eng)"
+// @Param ocr_psm formData int false "Page segmentation mode do Tesseract (padrão: 6)"
+// @Param ocr_oem formData int false "OCR engine mode do Tesseract, de 0 (legado) a 3; vazio usa o padrão"
+// @Param ocr_dpi formData int false "Resolução informada ao Tesseract"
+// @Param include_ocr_text formData bool false "Inclui o texto reconhecido pelo OCR no resultado de cada página"
+// @Param schema formData string false "Nome do esquema de extração registrado (ver GET /schemas); none desativa o padrão"
+// @Param schema_definition formData string false "Definição de esquema em JSON (name, description, fields), usada no lugar de schema"
+// @Param nocache query bool false "Ignora o cache de resultados e reprocessa todas as páginas (1 ou true)"
+// @Success 202 {object} entities.Job
+// @Failure 400 {object} map[string]string "Failed to receive the file"
+// @Failure 401 {object} map[string]string "X-API-Key fora de API_KEYS"
+// @Failure 402 {object} map[string]string "Limite de gasto com o LLM atingido"
+// @Failure 409 {object} map[string]string "Requisição com a mesma Idempotency-Key em andamento"
+// @Failure 422 {object} map[string]string "Idempotency-Key reutilizada com outra query string ou outro corpo"
+// @Failure 429 {object} map[string]string "Limite de requisições atingido (ver Retry-After e RateLimit-*)"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /jobs [post]
+func CreateJobHandler(c *fiber.Ctx) error {
+	upload, uploadErr := receivePDFUpload(c)
+	if uploadErr != nil {
+		return c.Status(uploadErr.Code).JSON(fiber.Map{"error": uploadErr.Message})
+	}
+
+	job, err := services.Jobs.Submit(c.UserContext(), upload.DocumentID, upload.Caller, upload.Path, upload.WorkDir, upload.Options)
+	if err != nil {
+		log.Printf("Erro ao criar job: %v", err)
+		services.WorkDirs.Remove(upload.WorkDir)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro ao criar job"})
+	}
+
+	auditJob(c, job)
+	return c.Status(fiber.StatusAccepted).JSON(job)
+}
+
+// GetJobHandler godoc
+// @Summary Consulta um job de processamento de PDF
+// @Description Retorna o status, o progresso por página e os resultados parciais do job
+// @Tags Jobs
+// @Produce json
+// @Param id path string true "ID do job"
+// @Success 200 {object} entities.Job
+// @Failure 404 {object} map[string]string "Job não encontrado"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /jobs/{id} [get]
+func GetJobHandler(c *fiber.Ctx) error {
+	job, err := services.Jobs.Get(c.UserContext(), c.Params("id"))
+	if err != nil {
+		return jobError(c, err)
+	}
+
+	return c.JSON(job)
+}
+
+// CancelJobHandler godoc
+// @Summary Cancela um job de processamento de PDF
+// @Description Interrompe o processamento; os resultados das páginas já concluídas são mantidos
+// @Tags Jobs
+// @Produce json
+// @Param id path string true "ID do job"
+// @Success 200 {object} entities.Job
+// @Failure 404 {object} map[string]string "Job não encontrado"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /jobs/{id} [delete]
+func CancelJobHandler(c *fiber.Ctx) error {
+	job, err := services.Jobs.Cancel(c.UserContext(), c.Params("id"))
+	if err != nil {
+		return jobError(c, err)
+	}
+
+	return c.JSON(job)
+}
+
+func jobError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, services.ErrJobNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Job não encontrado"})
+	}
+
+	log.Printf("Erro ao acessar job: %v", err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Internal Server Error"})
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"github.com/gofiber/fiber/v2/log"
 	"gosmart/services"
 
@@ -14,8 +15,15 @@ import (
 // @Accept json
 // @Produce json
 // @Param request body entities.OpenAIRequest true "Prompt para a OpenAI"
-// @Success 200 {object} map[string]string "Resposta gerada"
+// @Param Idempotency-Key header string false "Chave que torna repetições da requisição seguras: a resposta da primeira é devolvida sem reprocessar"
+// @Success 200 {object} map[string]interface{} "Resposta gerada, consumo de tokens (usage) e avisos de orçamento (warnings)"
 // @Failure 400 {object} map[string]string "Erro de validação"
+// @Failure 401 {object} map[string]string "X-API-Key fora de API_KEYS"
+// @Failure 402 {object} map[string]string "Limite de gasto com o LLM atingido"
+// @Failure 409 {object} map[string]string "Requisição com a mesma Idempotency-Key em andamento"
+// @Failure 422 {object} map[string]string "Idempotency-Key reutilizada com outra query string ou outro corpo"
+// @Failure 429 {object} map[string]string "Limite de requisições atingido (ver Retry-After e RateLimit-*)"
+// @Failure 413 {object} map[string]string "Prompt excede o orçamento de tokens"
 // @Failure 500 {object} map[string]string "Erro interno"
 // @Router /openai [post]
 func OpenAIHandler(c *fiber.Ctx) error {
@@ -28,11 +36,22 @@ func OpenAIHandler(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
 	}
 
-	response, err := services.GenerateText(req.Prompt)
+	ctx := services.WithUsageScope(c.UserContext(), services.CallerID(c.Get("X-API-Key")), "")
+	response, usage, err := services.GenerateText(ctx, req.Prompt)
+	if errors.Is(err, services.ErrPromptTooLarge) {
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{"error": err.Error()})
+	}
+	if errors.Is(err, services.ErrBudgetExceeded) {
+		return c.Status(fiber.StatusPaymentRequired).JSON(fiber.Map{"error": err.Error()})
+	}
 	if err != nil {
 		log.Error("Erro ao processar operação OpenAI: ", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	return c.JSON(fiber.Map{"response": response})
+	body := fiber.Map{"response": response, "usage": usage}
+	if warnings := services.Budgets.Warnings(ctx); len(warnings) > 0 {
+		body["warnings"] = warnings
+	}
+	return c.JSON(body)
 }
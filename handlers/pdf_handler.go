@@ -1,14 +1,16 @@
 package handlers
 
 import (
-	"fmt"
+	"errors"
 	"github.com/google/uuid"
+	"gosmart/entities"
+	"gosmart/ocr"
+	"gosmart/schema"
 	"gosmart/services"
 	"log"
-	"os"
-	"os/exec"
 	"path/filepath"
-	"sync"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -16,106 +18,226 @@ import (
 
 // ProcessPDFHandler godoc
 // @Summary Processa um arquivo PDF
-// @Description Recebe um arquivo PDF e processa cada página, retornando os resultados
+// @Description Recebe um arquivo PDF e processa cada página, retornando os resultados por página e a lista de produtos consolidada entre as páginas. Páginas com camada de texto nativa dispensam OCR; o campo method indica o caminho usado (text_layer, ocr ou vision). O texto do OCR e a extração de páginas já vistas são reaproveitados do cache; o campo cache indica hit ou miss por página
 // @Tags PDF
 // @Accept multipart/form-data
 // @Produce json
 // @Param file formData file true "PDF file to be processed"
-// @Success 200 {array} map[string]interface{}
+// @Param Idempotency-Key header string false "Chave que torna repetições da requisição seguras: a resposta da primeira é devolvida sem reprocessar"
+// @Param mode formData string false "Modo de extração: ocr (Tesseract + LLM) ou vision (somente LLM com visão)" Enums(ocr, vision)
+// @Param ocr_engine formData string false "Backend de OCR usado no modo ocr (padrão: OCR_ENGINE)" Enums(tesseract, vision, fake)
+// @Param ocr_lang formData string false "Idiomas do OCR separados por + (ex.: por+eng)"
+// @Param ocr_psm formData int false "Page segmentation mode do Tesseract (padrão: 6)"
+// @Param ocr_oem formData int false "OCR engine mode do Tesseract, de 0 (legado) a 3; vazio usa o padrão"
+// @Param ocr_dpi formData int false "Resolução informada ao Tesseract"
+// @Param include_ocr_text formData bool false "Inclui o texto reconhecido pelo OCR no resultado de cada página"
+// @Param schema formData string false "Nome do esquema de extração registrado (ver GET /schemas); none desativa o padrão"
+// @Param schema_definition formData string false "Definição de esquema em JSON (name, description, fields), usada no lugar de schema"
+// @Param nocache query bool false "Ignora o cache de resultados e reprocessa todas as páginas (1 ou true)"
+// @Success 200 {object} entities.DocumentResult
 // @Failure 400 {object} map[string]string "Failed to receive the file"
+// @Failure 401 {object} map[string]string "X-API-Key fora de API_KEYS"
+// @Failure 402 {object} map[string]string "Limite de gasto com o LLM atingido"
+// @Failure 409 {object} map[string]string "Requisição com a mesma Idempotency-Key em andamento"
+// @Failure 422 {object} map[string]string "Idempotency-Key reutilizada com outra query string ou outro corpo"
+// @Failure 429 {object} map[string]string "Limite de requisições atingido (ver Retry-After e RateLimit-*)"
 // @Failure 500 {object} map[string]string "Internal server error"
+// @Failure 504 {object} map[string]string "Tempo de espera esgotado; o job continua em /jobs/{id} (cabeçalho Location), e a resposta é repetida para a mesma Idempotency-Key"
 // @Router /process-pdf [post]
 func ProcessPDFHandler(c *fiber.Ctx) error {
 	currentTime := time.Now()
-	file, err := c.FormFile("file")
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Falha ao receber o arquivo"})
-	}
-
-	uniqueID := uuid.New().String()
 
-	tempDir := "./pdf_temp"
-	if err := os.MkdirAll(tempDir, os.ModePerm); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro ao criar diretório temporário"})
+	upload, uploadErr := receivePDFUpload(c)
+	if uploadErr != nil {
+		return c.Status(uploadErr.Code).JSON(fiber.Map{"error": uploadErr.Message})
 	}
 
-	tempFilePath := filepath.Join(tempDir, fmt.Sprintf("%s.pdf", uniqueID))
-	if err := c.SaveFile(file, tempFilePath); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro ao salvar arquivo PDF"})
+	// As páginas passam pela mesma fila global dos jobs assíncronos; aqui apenas aguardamos o resultado
+	job, err := services.Jobs.Submit(c.UserContext(), upload.DocumentID, upload.Caller, upload.Path, upload.WorkDir, upload.Options)
+	if err != nil {
+		log.Printf("Erro ao criar job: %v", err)
+		services.WorkDirs.Remove(upload.WorkDir)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro ao processar PDF"})
 	}
 
-	// Converte o PDF em imagens
-	imageFiles, err := convertPDFToImages(tempFilePath, tempDir, uniqueID)
+	job, err = services.Jobs.Wait(c.UserContext(), job.ID)
+	if errors.Is(err, services.ErrJobWaitTimeout) {
+		log.Printf("Tempo esgotado aguardando job %s", upload.DocumentID)
+		// Location indica ao middleware Idempotency que o trabalho continua: a resposta é guardada e
+		// repetida, em vez de uma nova tentativa iniciar outro job
+		c.Set(fiber.HeaderLocation, "/jobs/"+upload.DocumentID)
+		return c.Status(fiber.StatusGatewayTimeout).JSON(fiber.Map{
+			"error":  "Tempo de processamento esgotado; acompanhe o resultado em /jobs/" + upload.DocumentID,
+			"job_id": upload.DocumentID,
+		})
+	}
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro ao converter PDF para imagens"})
+		log.Printf("Erro ao aguardar job %s: %v", upload.DocumentID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro ao processar PDF"})
+	}
+	auditJob(c, job)
+	if job.Status == entities.JobStatusFailed {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": job.Error})
 	}
 
-	results := make([]map[string]interface{}, len(imageFiles))
-	var wg sync.WaitGroup
-	semaphore := make(chan struct{}, 2)
+	elapsedTime := time.Since(currentTime)
+	log.Printf("Tempo total de processamento: %v", elapsedTime)
+	return c.JSON(entities.DocumentResult{
+		DocumentID: job.ID,
+		Results:    job.Results,
+		FileHash:   job.FileHash,
+		Products:   job.Products,
+		Usage:      job.Usage,
+	})
+}
 
-	for i, imageFile := range imageFiles {
-		wg.Add(1)
-		semaphore <- struct{}{}
+type pdfUpload struct {
+	DocumentID string
+	Caller     string
+	WorkDir    string
+	Path       string
+	Options    services.ProcessingOptions
+}
 
-		go func(idx int, imgPath string) {
-			defer wg.Done()
-			defer func() { <-semaphore }()
+// receivePDFUpload valida o formulário e salva o PDF recebido no diretório de trabalho do job.
+func receivePDFUpload(c *fiber.Ctx) (*pdfUpload, *fiber.Error) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Falha ao receber o arquivo")
+	}
 
-			// Extrai texto da imagem usando Tesseract
-			extractedText, err := extractTextWithTesseract(imgPath)
-			if err != nil {
-				log.Printf("Erro ao extrair texto da imagem %d: %v", idx+1, err)
-				results[idx] = map[string]interface{}{"error": "Erro ao extrair texto da imagem"}
-				return
-			}
+	options, optionsErr := parseProcessingOptions(c)
+	if optionsErr != nil {
+		return nil, optionsErr
+	}
 
-			log.Printf("Texto extraído da imagem %d: %s", idx+1, extractedText)
+	// Recusa o documento logo no envio se o teto de gasto já foi atingido, em vez de falhar página por página
+	caller := services.CallerID(c.Get("X-API-Key"))
+	if err := services.Budgets.Check(services.WithUsageScope(c.UserContext(), caller, "")); err != nil {
+		return nil, fiber.NewError(fiber.StatusPaymentRequired, err.Error())
+	}
 
-			// Processa o texto com OpenAI
-			result, err := services.ProcessExtractedText(extractedText)
-			if err != nil {
-				log.Printf("Erro ao processar texto extraído da imagem %d: %v", idx+1, err)
-				results[idx] = map[string]interface{}{"error": "Erro ao processar texto extraído"}
-				return
-			}
+	uniqueID := uuid.New().String()
 
-			results[idx] = result
-		}(i, imageFile)
+	workDir, err := services.WorkDirs.Create(uniqueID)
+	if err != nil {
+		log.Printf("Erro ao criar diretório de trabalho: %v", err)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Erro ao criar diretório temporário")
 	}
 
-	wg.Wait()
+	tempFilePath := filepath.Join(workDir, "document.pdf")
+	if err := c.SaveFile(file, tempFilePath); err != nil {
+		services.WorkDirs.Remove(workDir)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Erro ao salvar arquivo PDF")
+	}
 
-	elapsedTime := time.Since(currentTime)
-	log.Printf("Tempo total de processamento: %v", elapsedTime)
-	return c.JSON(results)
+	return &pdfUpload{DocumentID: uniqueID, Caller: caller, WorkDir: workDir, Path: tempFilePath, Options: options}, nil
 }
 
-func convertPDFToImages(pdfPath string, outputDir string, uniqueID string) ([]string, error) {
-	imagesOutputDir := filepath.Join(outputDir, uniqueID+"_images")
-	if err := os.MkdirAll(imagesOutputDir, os.ModePerm); err != nil {
-		return nil, fmt.Errorf("erro ao criar diretório para imagens: %w", err)
+// parseProcessingOptions lê o modo de extração, o backend de OCR e as opções de OCR do formulário,
+// usando os padrões configurados para os campos ausentes.
+func parseProcessingOptions(c *fiber.Ctx) (services.ProcessingOptions, *fiber.Error) {
+	options := services.ProcessingOptions{
+		Mode:       c.FormValue("mode", services.ExtractionModeOCR),
+		OCREngine:  c.FormValue("ocr_engine", services.DefaultOCREngine),
+		OCROptions: services.DefaultOCROptions,
+		NoCache:    c.QueryBool("nocache"),
 	}
 
-	imagePattern := filepath.Join(imagesOutputDir, fmt.Sprintf("%s_page_%%d.png", uniqueID))
-	cmd := exec.Command("mutool", "draw", "-o", imagePattern, pdfPath)
-	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("erro ao converter PDF para imagens: %w", err)
+	if options.Mode != services.ExtractionModeOCR && options.Mode != services.ExtractionModeVision {
+		return options, fiber.NewError(fiber.StatusBadRequest, "Modo de extração inválido")
+	}
+	if _, err := ocr.Get(options.OCREngine); err != nil {
+		return options, fiber.NewError(fiber.StatusBadRequest, "Backend de OCR inválido")
 	}
 
-	imageFiles, err := filepath.Glob(filepath.Join(imagesOutputDir, fmt.Sprintf("%s_page_*.png", uniqueID)))
-	if err != nil {
-		return nil, fmt.Errorf("erro ao listar imagens geradas: %w", err)
+	extractionSchema, schemaErr := parseExtractionSchema(c)
+	if schemaErr != nil {
+		return options, schemaErr
+	}
+	options.Schema = extractionSchema
+
+	if value := c.FormValue("include_ocr_text"); value != "" {
+		include, err := strconv.ParseBool(value)
+		if err != nil {
+			return options, fiber.NewError(fiber.StatusBadRequest, "Valor inválido para include_ocr_text")
+		}
+		options.IncludeOCRText = include
+	}
+	if languages := c.FormValue("ocr_lang"); languages != "" {
+		options.OCROptions.Languages = strings.FieldsFunc(languages, func(r rune) bool { return r == '+' || r == ',' })
+	}
+	for field, target := range map[string]*int{
+		"ocr_psm": &options.OCROptions.PSM,
+		"ocr_dpi": &options.OCROptions.DPI,
+	} {
+		value := c.FormValue(field)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return options, fiber.NewError(fiber.StatusBadRequest, "Valor inválido para "+field)
+		}
+		*target = parsed
+	}
+	// 0 é um OEM válido (motor legado), então a ausência do campo é distinguida pelo ponteiro nil
+	if value := c.FormValue("ocr_oem"); value != "" {
+		oem, err := strconv.Atoi(value)
+		if err != nil {
+			return options, fiber.NewError(fiber.StatusBadRequest, "Valor inválido para ocr_oem")
+		}
+		options.OCROptions.OEM = &oem
+	}
+	if err := options.OCROptions.Validate(); err != nil {
+		return options, fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
-	return imageFiles, nil
+	return options, nil
 }
 
-func extractTextWithTesseract(imagePath string) (string, error) {
-	cmd := exec.Command("tesseract", imagePath, "stdout", "--psm", "6") // --psm 6 é ideal para tabelas
-	output, err := cmd.Output()
-	if err != nil {
-		return "", fmt.Errorf("erro ao executar tesseract: %w", err)
+// parseExtractionSchema resolve o esquema de extração: uma definição enviada em schema_definition,
+// um esquema registrado escolhido em schema ou, sem nenhum dos dois, o padrão de EXTRACTION_SCHEMA.
+// O valor "none" em schema desativa o esquema padrão.
+func parseExtractionSchema(c *fiber.Ctx) (*schema.Schema, *fiber.Error) {
+	if definition := c.FormValue("schema_definition"); definition != "" {
+		extractionSchema, err := schema.Parse([]byte(definition))
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		return extractionSchema, nil
 	}
-	return string(output), nil
+
+	switch name := c.FormValue("schema"); name {
+	case "":
+		return services.DefaultSchema, nil
+	case "none":
+		return nil, nil
+	default:
+		extractionSchema, err := schema.Get(name)
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		return extractionSchema, nil
+	}
+}
+
+// auditJob completa o evento de auditoria da requisição com o documento e o consumo do job.
+// As páginas rodam nos workers da fila, então o consumo vem do agregado do documento.
+func auditJob(c *fiber.Ctx, job *entities.Job) {
+	services.AnnotateAudit(c.UserContext(), func(event *entities.AuditEvent) {
+		event.DocumentID = job.ID
+		event.DocumentHash = job.FileHash
+		for _, result := range job.Results {
+			if result != nil && result.Model != "" {
+				event.Model = result.Model
+				break
+			}
+		}
+		if job.Usage != nil {
+			event.PromptTokens = job.Usage.PromptTokens
+			event.CompletionTokens = job.Usage.CompletionTokens
+			event.TotalTokens = job.Usage.TotalTokens
+		}
+	})
 }
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"gosmart/services"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// QueueStatsHandler godoc
+// @Summary Consulta a fila de páginas
+// @Description Retorna quantas páginas aguardam processamento, quantas estão com os workers e quantos jobs estão ativos
+// @Tags Jobs
+// @Produce json
+// @Success 200 {object} services.QueueStats
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /queue [get]
+func QueueStatsHandler(c *fiber.Ctx) error {
+	stats, err := services.Pages.Stats(c.UserContext())
+	if err != nil {
+		log.Printf("Erro ao consultar fila: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Internal Server Error"})
+	}
+
+	return c.JSON(stats)
+}
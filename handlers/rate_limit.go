@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"gosmart/services"
+	"log"
+	"math"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RateLimit aplica o limite name por chamador (ver services.CallerID) e informa a situação nos cabeçalhos
+// RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset e RateLimit-Policy. Acima do limite responde
+// 429 com Retry-After. Com o Redis indisponível, as requisições passam sem limite.
+func RateLimit(name string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !services.RedisAvailable() {
+			return c.Next()
+		}
+
+		result, err := services.RateLimits.Allow(c.UserContext(), name, services.CallerID(c.Get("X-API-Key")))
+		if err != nil {
+			log.Printf("Limite de requisições ignorado: %v", err)
+			return c.Next()
+		}
+		if result == nil {
+			return c.Next()
+		}
+
+		limit, _ := services.RateLimits.Limit(name)
+		reset := strconv.Itoa(int(math.Ceil(result.Reset.Seconds())))
+		c.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Set("RateLimit-Reset", reset)
+		c.Set("RateLimit-Policy", limit.Policy())
+
+		if !result.Allowed {
+			c.Set(fiber.HeaderRetryAfter, reset)
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "Limite de requisições atingido, tente novamente em " + reset + "s"})
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,18 @@
+package handlers
+
+import (
+	"gosmart/schema"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ListSchemasHandler godoc
+// @Summary Lista os esquemas de extração
+// @Description Retorna os esquemas de extração registrados, que podem ser escolhidos pelo campo schema de /process-pdf e /jobs
+// @Tags PDF
+// @Produce json
+// @Success 200 {array} schema.Schema
+// @Router /schemas [get]
+func ListSchemasHandler(c *fiber.Ctx) error {
+	return c.JSON(schema.List())
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"gosmart/entities"
+	"gosmart/services"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const usageDateLayout = "2006-01-02"
+
+// UsageReportHandler godoc
+// @Summary Relatório de consumo do LLM
+// @Description Retorna chamadas, tokens e custo estimado por dia e por chamador no período (datas em UTC, inclusive). Com format=csv, devolve o relatório como arquivo CSV
+// @Tags Usage
+// @Produce json
+// @Produce text/csv
+// @Param X-Admin-Key header string true "Chave de administração (ADMIN_API_KEY)"
+// @Param from query string false "Data inicial (YYYY-MM-DD, padrão: 30 dias antes de to)"
+// @Param to query string false "Data final (YYYY-MM-DD, padrão: hoje)"
+// @Param caller query string false "Filtra por chamador (nome em API_KEYS ou anonymous)"
+// @Param format query string false "Formato da resposta" Enums(json, csv)
+// @Success 200 {object} entities.UsageReport
+// @Failure 400 {object} map[string]string "Parâmetros inválidos"
+// @Failure 401 {object} map[string]string "Chave de administração inválida"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /usage [get]
+func UsageReportHandler(c *fiber.Ctx) error {
+	to := time.Now().UTC()
+	if value := c.Query("to"); value != "" {
+		parsed, err := time.Parse(usageDateLayout, value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Data inválida em to (use YYYY-MM-DD)"})
+		}
+		to = parsed
+	}
+	from := to.AddDate(0, 0, -30)
+	if value := c.Query("from"); value != "" {
+		parsed, err := time.Parse(usageDateLayout, value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Data inválida em from (use YYYY-MM-DD)"})
+		}
+		from = parsed
+	}
+
+	format := c.Query("format", "json")
+	if format != "json" && format != "csv" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Formato inválido"})
+	}
+	if from.After(to) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "from deve ser anterior ou igual a to"})
+	}
+
+	report, err := services.Usage.Report(c.UserContext(), from, to, c.Query("caller"))
+	var rangeErr *services.UsageRangeError
+	if errors.As(err, &rangeErr) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": rangeErr.Error()})
+	}
+	if err != nil {
+		log.Printf("Erro ao gerar relatório de consumo: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Internal Server Error"})
+	}
+
+	if format == "csv" {
+		return writeUsageCSV(c, report)
+	}
+	return c.JSON(report)
+}
+
+// DocumentUsageHandler godoc
+// @Summary Consumo do LLM de um documento
+// @Description Retorna chamadas, tokens e custo estimado acumulados no processamento de um documento (ID do job)
+// @Tags Usage
+// @Produce json
+// @Param X-Admin-Key header string true "Chave de administração (ADMIN_API_KEY)"
+// @Param id path string true "ID do documento"
+// @Success 200 {object} entities.DocumentUsage
+// @Failure 401 {object} map[string]string "Chave de administração inválida"
+// @Failure 404 {object} map[string]string "Nenhum consumo registrado"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /usage/documents/{id} [get]
+func DocumentUsageHandler(c *fiber.Ctx) error {
+	usage, err := services.Usage.Document(c.UserContext(), c.Params("id"))
+	if err != nil {
+		log.Printf("Erro ao ler consumo do documento: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Internal Server Error"})
+	}
+	if usage.Calls == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Nenhum consumo registrado para o documento"})
+	}
+
+	return c.JSON(usage)
+}
+
+func writeUsageCSV(c *fiber.Ctx, report *entities.UsageReport) error {
+	c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="usage_%s_%s.csv"`, report.From, report.To))
+
+	writer := csv.NewWriter(c)
+	writer.Write([]string{"date", "caller", "calls", "prompt_tokens", "completion_tokens", "total_tokens", "cost_usd"})
+	for _, row := range report.Rows {
+		writer.Write([]string{
+			row.Date,
+			row.Caller,
+			strconv.FormatInt(row.Calls, 10),
+			strconv.FormatInt(row.PromptTokens, 10),
+			strconv.FormatInt(row.CompletionTokens, 10),
+			strconv.FormatInt(row.TotalTokens, 10),
+			strconv.FormatFloat(row.CostUSD, 'f', 6, 64),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"encoding/json"
+	"gosmart/services"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestUsageReportHandlerErrors(t *testing.T) {
+	// Redis inacessível: erros de leitura devem virar 500 sem expor a mensagem
+	t.Setenv("REDIS_URL", "redis://127.0.0.1:1/0")
+	t.Setenv("REDIS_CONNECT_RETRIES", "0")
+	t.Setenv("REDIS_HEALTH_INTERVAL", "0")
+	services.InitRedis()
+	services.InitUsage()
+
+	app := fiber.New()
+	app.Get("/usage", UsageReportHandler)
+
+	tests := []struct {
+		name   string
+		target string
+		status int
+		error  string
+	}{
+		{name: "data inválida", target: "/usage?from=ontem", status: fiber.StatusBadRequest, error: "Data inválida em from (use YYYY-MM-DD)"},
+		{name: "intervalo longo demais", target: "/usage?from=2024-01-01&to=2025-12-31", status: fiber.StatusBadRequest, error: "intervalo inválido: 731 dias excedem o máximo de 366"},
+		{name: "erro do Redis", target: "/usage?from=2025-01-01&to=2025-01-02", status: fiber.StatusInternalServerError, error: "Internal Server Error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := app.Test(httptest.NewRequest(fiber.MethodGet, tt.target, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var body map[string]string
+			json.NewDecoder(response.Body).Decode(&body)
+			if response.StatusCode != tt.status || body["error"] != tt.error {
+				t.Errorf("resposta = %d %q, esperado %d %q", response.StatusCode, body["error"], tt.status, tt.error)
+			}
+		})
+	}
+}
@@ -0,0 +1,253 @@
+package jsonrepair
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Reparos que Extract pode aplicar, informados na ordem em que foram feitos.
+const (
+	RepairCodeFences      = "code_fences"
+	RepairSurroundingText = "surrounding_text"
+	RepairSingleQuotes    = "single_quotes"
+	RepairTrailingCommas  = "trailing_commas"
+	RepairControlChars    = "control_chars"
+	RepairTruncated       = "truncated"
+)
+
+var (
+	ErrNoJSON     = errors.New("nenhum JSON encontrado na resposta")
+	ErrTruncated  = errors.New("JSON incompleto na resposta")
+	ErrUnrepaired = errors.New("JSON inválido mesmo após os reparos")
+)
+
+// Result é o JSON extraído da resposta e a lista de reparos aplicados; Repairs vazio indica JSON puro.
+type Result struct {
+	JSON    []byte
+	Repairs []string
+}
+
+// Extract encontra o JSON na resposta do modelo: remove cercas de código (```json), ignora texto antes e
+// depois do valor mais externo, troca aspas simples por duplas, escapa caracteres de controle dentro das
+// strings e remove vírgulas finais. Com truncated
+// (finish_reason "length"), um JSON cortado é fechado descartando o último item incompleto.
+func Extract(content string, truncated bool) (*Result, error) {
+	trimmed := strings.TrimSpace(content)
+	if json.Valid([]byte(trimmed)) {
+		return &Result{JSON: []byte(trimmed)}, nil
+	}
+
+	r := &repairer{}
+	text := r.stripFences(trimmed)
+
+	start := strings.IndexAny(text, "{[")
+	if start < 0 {
+		return nil, ErrNoJSON
+	}
+	if strings.TrimSpace(text[:start]) != "" {
+		r.note(RepairSurroundingText)
+	}
+
+	out, err := r.rewrite(text[start:], truncated)
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid(out) {
+		return nil, ErrUnrepaired
+	}
+	return &Result{JSON: out, Repairs: r.repairs}, nil
+}
+
+// Unmarshal extrai o JSON com Extract e o decodifica em out.
+func Unmarshal(content string, truncated bool, out interface{}) ([]string, error) {
+	result, err := Extract(content, truncated)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(result.JSON, out); err != nil {
+		return result.Repairs, err
+	}
+	return result.Repairs, nil
+}
+
+type repairer struct {
+	repairs []string
+}
+
+func (r *repairer) note(repair string) {
+	for _, existing := range r.repairs {
+		if existing == repair {
+			return
+		}
+	}
+	r.repairs = append(r.repairs, repair)
+}
+
+// stripFences devolve o conteúdo do primeiro bloco ```...```; sem a cerca de fechamento, todo o resto.
+func (r *repairer) stripFences(text string) string {
+	open := strings.Index(text, "```")
+	if open < 0 {
+		return text
+	}
+	r.note(RepairCodeFences)
+
+	body := text[open+3:]
+	if newline := strings.IndexByte(body, '\n'); newline >= 0 && !strings.ContainsAny(body[:newline], "{[") {
+		body = body[newline+1:] // descarta a linguagem (```json)
+	}
+	if end := strings.Index(body, "```"); end >= 0 {
+		if strings.TrimSpace(body[end+3:]) != "" || strings.TrimSpace(text[:open]) != "" {
+			r.note(RepairSurroundingText)
+		}
+		return body[:end]
+	}
+	return body
+}
+
+// cutPoint é uma posição da saída em que o JSON pode ser fechado sem deixar um valor pela metade.
+type cutPoint struct {
+	length int
+	stack  []byte
+}
+
+// rewrite percorre o valor mais externo reescrevendo-o como JSON válido.
+func (r *repairer) rewrite(text string, truncated bool) ([]byte, error) {
+	var out []byte
+	var stack []byte
+	var quote byte
+	var lastCut, lastWholeCut *cutPoint
+
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+
+		if quote != 0 {
+			switch {
+			case c == '\\' && i+1 < len(text):
+				if text[i+1] == '\'' {
+					// \' não é um escape válido em JSON
+					out = append(out, '\'')
+				} else {
+					out = append(out, c, text[i+1])
+				}
+				i++
+			case c == '\'' && quote == '\'' && followedByLetter(text, i):
+				// Apóstrofo dentro da palavra (d'água): não fecha a string
+				out = append(out, c)
+			case c == quote:
+				out = append(out, '"')
+				quote = 0
+			case c == '"':
+				out = append(out, '\\', '"')
+			case c < 0x20:
+				out = r.appendControl(out, c)
+			default:
+				out = append(out, c)
+			}
+			continue
+		}
+
+		switch c {
+		case '"', '\'':
+			if c == '\'' {
+				r.note(RepairSingleQuotes)
+			}
+			quote = c
+			out = append(out, '"')
+		case '{', '[':
+			stack = append(stack, c)
+			out = append(out, c)
+		case '}', ']':
+			out = r.dropTrailingComma(out)
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			out = append(out, c)
+			if len(stack) == 0 {
+				if strings.TrimSpace(text[i+1:]) != "" {
+					r.note(RepairSurroundingText)
+				}
+				return out, nil
+			}
+		case ',':
+			cut := &cutPoint{length: len(out), stack: append([]byte(nil), stack...)}
+			lastCut = cut
+			if !insideListItem(stack) {
+				lastWholeCut = cut
+			}
+			out = append(out, c)
+		default:
+			out = append(out, c)
+		}
+	}
+
+	// O texto acabou com o valor aberto: a resposta foi cortada
+	if !truncated {
+		return nil, ErrTruncated
+	}
+	// O corte mais adiante que não divide um item de lista preserva todos os itens e campos completos;
+	// só sem ele o último item é fechado pela metade
+	cut := lastWholeCut
+	if cut == nil {
+		cut = lastCut
+	}
+	if cut == nil {
+		return nil, ErrTruncated
+	}
+
+	r.note(RepairTruncated)
+	out = out[:cut.length]
+	for i := len(cut.stack) - 1; i >= 0; i-- {
+		out = r.dropTrailingComma(out)
+		if cut.stack[i] == '{' {
+			out = append(out, '}')
+		} else {
+			out = append(out, ']')
+		}
+	}
+	return out, nil
+}
+
+// followedByLetter indica se a aspa na posição i é seguida por uma letra.
+func followedByLetter(text string, i int) bool {
+	next, _ := utf8.DecodeRuneInString(text[i+1:])
+	return unicode.IsLetter(next)
+}
+
+// insideListItem indica se a pilha está dentro de um objeto que é item da lista mais interna, como um produto.
+func insideListItem(stack []byte) bool {
+	list := bytes.LastIndexByte(stack, '[')
+	return list >= 0 && bytes.IndexByte(stack[list:], '{') >= 0
+}
+
+// appendControl escreve um caractere de controle cru, inválido dentro de strings JSON, como escape.
+func (r *repairer) appendControl(out []byte, c byte) []byte {
+	r.note(RepairControlChars)
+	switch c {
+	case '\n':
+		return append(out, '\\', 'n')
+	case '\r':
+		return append(out, '\\', 'r')
+	case '\t':
+		return append(out, '\\', 't')
+	default:
+		return append(out, fmt.Sprintf("\\u%04x", c)...)
+	}
+}
+
+// dropTrailingComma remove uma vírgula final (ignorando espaços) antes do fechamento de objeto ou lista.
+func (r *repairer) dropTrailingComma(out []byte) []byte {
+	end := len(out)
+	for end > 0 && strings.IndexByte(" \t\r\n", out[end-1]) >= 0 {
+		end--
+	}
+	if end > 0 && out[end-1] == ',' {
+		r.note(RepairTrailingCommas)
+		return append(out[:end-1], out[end:]...)
+	}
+	return out
+}
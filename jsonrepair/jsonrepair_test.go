@@ -0,0 +1,152 @@
+package jsonrepair
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		truncated bool
+		want      string
+		repairs   []string
+		err       error
+	}{
+		{
+			name:    "JSON puro",
+			content: `{"codigo": "1", "preco": 10}`,
+			want:    `{"codigo": "1", "preco": 10}`,
+		},
+		{
+			name:    "cerca de código",
+			content: "```json\n{\"codigo\": \"1\"}\n```",
+			want:    `{"codigo": "1"}`,
+			repairs: []string{RepairCodeFences},
+		},
+		{
+			name:    "cerca sem fechamento",
+			content: "```json\n[1, 2]",
+			want:    `[1, 2]`,
+			repairs: []string{RepairCodeFences},
+		},
+		{
+			name:    "texto antes e depois",
+			content: "Segue o JSON: {\"codigo\": \"1\"} Espero ter ajudado.",
+			want:    `{"codigo": "1"}`,
+			repairs: []string{RepairSurroundingText},
+		},
+		{
+			name:    "texto em volta da cerca",
+			content: "Resultado:\n```json\n{\"a\": 1}\n```\nFim",
+			want:    `{"a": 1}`,
+			repairs: []string{RepairCodeFences, RepairSurroundingText},
+		},
+		{
+			name:    "aspas simples",
+			content: `{'codigo': '1', 'descricao': 'Parafuso "sextavado"'}`,
+			want:    `{"codigo": "1", "descricao": "Parafuso \"sextavado\""}`,
+			repairs: []string{RepairSingleQuotes},
+		},
+		{
+			name:    "apóstrofo dentro de string com aspas simples",
+			content: `{'nome': 'Pão d'água'}`,
+			want:    `{"nome": "Pão d'água"}`,
+			repairs: []string{RepairSingleQuotes},
+		},
+		{
+			name:    "apóstrofo escapado",
+			content: `{'nome': 'Pão d\'água'}`,
+			want:    `{"nome": "Pão d'água"}`,
+			repairs: []string{RepairSingleQuotes},
+		},
+		{
+			name:    "caracteres de controle crus",
+			content: "{\"descricao\": \"linha 1\nlinha 2\tcoluna\r\x01fim\",}",
+			want:    `{"descricao": "linha 1\nlinha 2\tcoluna\r\u0001fim"}`,
+			repairs: []string{RepairControlChars, RepairTrailingCommas},
+		},
+		{
+			name:    "vírgulas finais",
+			content: `{"itens": [1, 2, 3,], "total": 6,}`,
+			want:    `{"itens": [1, 2, 3], "total": 6}`,
+			repairs: []string{RepairTrailingCommas},
+		},
+		{
+			name:      "resposta cortada descarta o último item",
+			content:   `{"itens": [{"codigo": "1"}, {"codigo": "2"}, {"codigo": "3", "desc`,
+			truncated: true,
+			want:      `{"itens": [{"codigo": "1"}, {"codigo": "2"}]}`,
+			repairs:   []string{RepairTruncated},
+		},
+		{
+			name:      "resposta cortada em objeto",
+			content:   `{"a": 1, "b": "tex`,
+			truncated: true,
+			want:      `{"a": 1}`,
+			repairs:   []string{RepairTruncated},
+		},
+		{
+			name:      "resposta cortada depois da lista mantém os campos completos",
+			content:   `{"itens": [{"codigo": "1"}, {"codigo": "2"}], "total": 2, "observacao": "tex`,
+			truncated: true,
+			want:      `{"itens": [{"codigo": "1"}, {"codigo": "2"}], "total": 2}`,
+			repairs:   []string{RepairTruncated},
+		},
+		{
+			name:      "resposta cortada em lista aninhada",
+			content:   `{"paginas": [{"itens": [{"codigo": "1"}, {"codigo": "2", "desc`,
+			truncated: true,
+			want:      `{"paginas": [{"itens": [{"codigo": "1"}]}]}`,
+			repairs:   []string{RepairTruncated},
+		},
+		{
+			name:    "cortada sem finish_reason length",
+			content: `{"itens": [{"codigo": "1"}, {"codigo": "2"`,
+			err:     ErrTruncated,
+		},
+		{
+			name:      "cortada sem ponto de corte",
+			content:   `{"itens": [{"codigo": "1"`,
+			truncated: true,
+			err:       ErrTruncated,
+		},
+		{
+			name:    "sem JSON",
+			content: "Não encontrei produtos nesta página.",
+			err:     ErrNoJSON,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Extract(tt.content, tt.truncated)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("erro = %v, esperado %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("erro inesperado: %v", err)
+			}
+
+			var got, want interface{}
+			if err := json.Unmarshal(result.JSON, &got); err != nil {
+				t.Fatalf("JSON inválido %s: %v", result.JSON, err)
+			}
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("JSON = %s, esperado %s", result.JSON, tt.want)
+			}
+			if !reflect.DeepEqual(result.Repairs, tt.repairs) {
+				t.Errorf("reparos = %v, esperado %v", result.Repairs, tt.repairs)
+			}
+		})
+	}
+}
@@ -1,9 +1,13 @@
 package main
 
 import (
+	"context"
 	"gosmart/config"
 	"gosmart/router"
 	"gosmart/services"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/log"
@@ -23,6 +27,32 @@ import (
 func main() {
 	config.LoadEnv()
 	services.InitRedis()
+	services.InitLLMClient()
+	services.InitModelCache()
+	services.InitModelPolicies()
+	services.InitJobs()
+	services.InitPageQueue()
+	services.InitWorkDirs()
+	services.InitOCR()
+	services.InitRasterizer()
+	services.InitSchemas()
+	services.InitCallers()
+	services.InitUsage()
+	services.InitBudgets()
+	services.InitResultCache()
+	services.InitIdempotency()
+	services.InitAudit()
+	services.InitRateLimits()
+
+	// `gosmart worker` roda apenas os workers da fila de páginas, sem a API HTTP
+	if len(os.Args) > 1 && os.Args[1] == "worker" {
+		runWorker()
+		return
+	}
+
+	go services.WorkDirs.StartJanitor(context.Background())
+	services.Pages.StartWorkers(context.Background(), services.WorkerCountFromEnv())
+	services.Jobs.Resume(context.Background())
 
 	log.Info("Servidor iniciado na porta 3000")
 
@@ -30,7 +60,8 @@ func main() {
 	app.Use(func(c *fiber.Ctx) error {
 		c.Set("Access-Control-Allow-Origin", "*")
 		c.Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE")
-		c.Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key, X-Admin-Key, Idempotency-Key, X-Request-ID")
+		c.Set("Access-Control-Expose-Headers", "X-Request-ID, Idempotent-Replayed, Retry-After, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, RateLimit-Policy")
 		return c.Next()
 	})
 
@@ -39,3 +70,20 @@ func main() {
 
 	log.Fatal(app.Listen(":3000"))
 }
+
+func runWorker() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	count := services.WorkerCountFromEnv()
+	if count == 0 {
+		log.Fatal("WORKER_COUNT deve ser maior que zero no modo worker")
+	}
+
+	go services.WorkDirs.StartJanitor(ctx)
+	workers := services.Pages.StartWorkers(ctx, count)
+	<-ctx.Done()
+
+	log.Info("Encerrando workers, aguardando páginas em andamento")
+	workers.Wait()
+}
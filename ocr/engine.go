@@ -0,0 +1,82 @@
+package ocr
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// Options ajusta o reconhecimento de uma página. Campos zerados usam o padrão do backend; OEM é um
+// ponteiro porque 0 (motor legado do Tesseract) é um valor válido, e nil usa o padrão.
+type Options struct {
+	Languages []string `json:"languages,omitempty"`
+	PSM       int      `json:"psm,omitempty"`
+	OEM       *int     `json:"oem,omitempty"`
+	DPI       int      `json:"dpi,omitempty"`
+}
+
+// Engine extrai o texto de uma imagem de página.
+type Engine interface {
+	Name() string
+	ExtractText(ctx context.Context, imagePath string, options Options) (string, error)
+}
+
+var languagePattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// Validate verifica se as opções estão dentro dos valores aceitos pelo Tesseract.
+func (o Options) Validate() error {
+	for _, language := range o.Languages {
+		if !languagePattern.MatchString(language) {
+			return fmt.Errorf("idioma de OCR inválido: %q", language)
+		}
+	}
+	if o.PSM < 0 || o.PSM > 13 {
+		return fmt.Errorf("psm inválido: %d", o.PSM)
+	}
+	if o.OEM != nil && (*o.OEM < 0 || *o.OEM > 3) {
+		return fmt.Errorf("oem inválido: %d", *o.OEM)
+	}
+	if o.DPI < 0 || o.DPI > 1200 {
+		return fmt.Errorf("dpi inválido: %d", o.DPI)
+	}
+	return nil
+}
+
+var (
+	mu      sync.RWMutex
+	engines = map[string]Engine{}
+)
+
+// Register disponibiliza um backend de OCR pelo nome retornado em Name.
+func Register(engine Engine) {
+	mu.Lock()
+	defer mu.Unlock()
+	engines[engine.Name()] = engine
+}
+
+// Get devolve o backend registrado com o nome informado.
+func Get(name string) (Engine, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	engine, ok := engines[name]
+	if !ok {
+		return nil, fmt.Errorf("backend de OCR desconhecido: %s", name)
+	}
+	return engine, nil
+}
+
+// Names lista os backends registrados.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := make([]string, 0, len(engines))
+	for name := range engines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
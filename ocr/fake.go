@@ -0,0 +1,28 @@
+package ocr
+
+import (
+	"context"
+	"path/filepath"
+)
+
+// Fake devolve textos fixos, sem executar OCR. Útil em testes e ambientes sem Tesseract.
+// Texts é indexado pelo nome do arquivo da imagem; imagens sem entrada recebem Default.
+type Fake struct {
+	Texts   map[string]string
+	Default string
+	Err     error
+}
+
+func (f *Fake) Name() string {
+	return "fake"
+}
+
+func (f *Fake) ExtractText(_ context.Context, imagePath string, _ Options) (string, error) {
+	if f.Err != nil {
+		return "", f.Err
+	}
+	if text, ok := f.Texts[filepath.Base(imagePath)]; ok {
+		return text, nil
+	}
+	return f.Default, nil
+}
@@ -0,0 +1,50 @@
+package ocr
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Tesseract executa o binário tesseract instalado no sistema.
+type Tesseract struct {
+	Binary string
+}
+
+func NewTesseract(binary string) *Tesseract {
+	if binary == "" {
+		binary = "tesseract"
+	}
+	return &Tesseract{Binary: binary}
+}
+
+func (t *Tesseract) Name() string {
+	return "tesseract"
+}
+
+func (t *Tesseract) ExtractText(ctx context.Context, imagePath string, options Options) (string, error) {
+	psm := options.PSM
+	if psm == 0 {
+		psm = 6 // --psm 6 é ideal para tabelas
+	}
+
+	args := []string{imagePath, "stdout", "--psm", strconv.Itoa(psm)}
+	if options.OEM != nil {
+		args = append(args, "--oem", strconv.Itoa(*options.OEM))
+	}
+	if len(options.Languages) > 0 {
+		args = append(args, "-l", strings.Join(options.Languages, "+"))
+	}
+	if options.DPI > 0 {
+		args = append(args, "--dpi", strconv.Itoa(options.DPI))
+	}
+
+	cmd := exec.CommandContext(ctx, t.Binary, args...)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("erro ao executar tesseract: %w", err)
+	}
+	return string(output), nil
+}
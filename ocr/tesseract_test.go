@@ -0,0 +1,63 @@
+package ocr
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestTesseractArguments(t *testing.T) {
+	echo, err := exec.LookPath("echo")
+	if err != nil {
+		t.Skip("echo indisponível")
+	}
+	// Com echo no lugar do tesseract, a saída são os argumentos da chamada
+	engine := NewTesseract(echo)
+
+	zero, one := 0, 1
+	tests := []struct {
+		name    string
+		options Options
+		want    string
+	}{
+		{name: "padrões", options: Options{}, want: "page.png stdout --psm 6"},
+		{name: "OEM 0", options: Options{OEM: &zero}, want: "page.png stdout --psm 6 --oem 0"},
+		{name: "todas as opções", options: Options{PSM: 4, OEM: &one, Languages: []string{"por", "eng"}, DPI: 300}, want: "page.png stdout --psm 4 --oem 1 -l por+eng --dpi 300"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := engine.ExtractText(context.Background(), "page.png", tt.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got = strings.TrimSpace(got); got != tt.want {
+				t.Errorf("argumentos = %q, esperado %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	zero, four := 0, 4
+	tests := []struct {
+		name    string
+		options Options
+		valid   bool
+	}{
+		{name: "vazias", options: Options{}, valid: true},
+		{name: "OEM 0", options: Options{OEM: &zero}, valid: true},
+		{name: "OEM fora do intervalo", options: Options{OEM: &four}},
+		{name: "PSM fora do intervalo", options: Options{PSM: 14}},
+		{name: "idioma inválido", options: Options{Languages: []string{"por;rm"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.options.Validate(); (err == nil) != tt.valid {
+				t.Errorf("Validate = %v, válido esperado %v", err, tt.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package ocr
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// TranscribeFunc envia a imagem a um modelo de visão e devolve o texto transcrito.
+type TranscribeFunc func(ctx context.Context, mimeType string, image []byte) (string, error)
+
+// Vision usa um LLM com visão como mecanismo de OCR. A chamada ao modelo é injetada
+// para que este pacote não dependa do cliente da OpenAI.
+type Vision struct {
+	transcribe TranscribeFunc
+}
+
+func NewVision(transcribe TranscribeFunc) *Vision {
+	return &Vision{transcribe: transcribe}
+}
+
+func (v *Vision) Name() string {
+	return "vision"
+}
+
+func (v *Vision) ExtractText(ctx context.Context, imagePath string, _ Options) (string, error) {
+	image, err := os.ReadFile(imagePath)
+	if err != nil {
+		return "", fmt.Errorf("erro ao ler a imagem: %w", err)
+	}
+	return v.transcribe(ctx, http.DetectContentType(image), image)
+}
@@ -0,0 +1,91 @@
+package rasterizer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+// Embedded é o fallback em Go puro: em vez de renderizar a página, extrai com o pdfcpu
+// a maior imagem embutida nela. Atende PDFs digitalizados, em que cada página é uma imagem,
+// mas não renderiza texto vetorial nem aplica DPI ou escala de cinza.
+type Embedded struct{}
+
+func NewEmbedded() *Embedded {
+	return &Embedded{}
+}
+
+func (e *Embedded) Name() string {
+	return "embedded"
+}
+
+func (e *Embedded) Binaries() []string {
+	return nil
+}
+
+func (e *Embedded) Rasterize(ctx context.Context, pdfPath string, outputDir string, options Options) ([]Page, error) {
+	file, err := os.Open(pdfPath)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao abrir PDF: %w", err)
+	}
+	defer file.Close()
+
+	var selectedPages []string
+	for _, r := range pageRanges(options.Pages) {
+		selectedPages = append(selectedPages, fmt.Sprintf("%d-%d", r[0], r[1]))
+	}
+
+	conf := model.NewDefaultConfiguration()
+	conf.ValidationMode = model.ValidationRelaxed
+
+	pageImages, err := api.ExtractImagesRaw(file, selectedPages, conf)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao extrair imagens do PDF: %w", err)
+	}
+
+	var pages []Page
+	for _, candidates := range pageImages {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		var largest *model.Image
+		for objNr := range candidates {
+			candidate := candidates[objNr]
+			if largest == nil || candidate.Width*candidate.Height > largest.Width*largest.Height {
+				largest = &candidate
+			}
+		}
+		if largest == nil {
+			continue
+		}
+
+		path := filepath.Join(outputDir, "page_"+strconv.Itoa(largest.PageNr)+"."+largest.FileType)
+		if err := writeImage(path, largest); err != nil {
+			return nil, err
+		}
+		pages = append(pages, Page{Number: largest.PageNr, Path: path})
+	}
+
+	sortPages(pages)
+	return pages, nil
+}
+
+func writeImage(path string, image io.Reader) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("erro ao gravar imagem: %w", err)
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, image); err != nil {
+		return fmt.Errorf("erro ao gravar imagem: %w", err)
+	}
+	return nil
+}
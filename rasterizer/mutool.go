@@ -0,0 +1,85 @@
+package rasterizer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Mutool rasteriza com o `mutool draw` do MuPDF.
+type Mutool struct {
+	Binary string
+}
+
+func NewMutool(binary string) *Mutool {
+	if binary == "" {
+		binary = "mutool"
+	}
+	return &Mutool{Binary: binary}
+}
+
+func (m *Mutool) Name() string {
+	return "mutool"
+}
+
+func (m *Mutool) Binaries() []string {
+	return []string{m.Binary}
+}
+
+func (m *Mutool) Rasterize(ctx context.Context, pdfPath string, outputDir string, options Options) ([]Page, error) {
+	args := []string{"draw", "-o", filepath.Join(outputDir, "page_%d.png")}
+	if options.DPI > 0 {
+		args = append(args, "-r", strconv.Itoa(options.DPI))
+	}
+	if options.Grayscale {
+		args = append(args, "-c", "gray")
+	}
+	args = append(args, pdfPath)
+
+	if len(options.Pages) > 0 {
+		var ranges []string
+		for _, r := range pageRanges(options.Pages) {
+			if r[0] == r[1] {
+				ranges = append(ranges, strconv.Itoa(r[0]))
+			} else {
+				ranges = append(ranges, fmt.Sprintf("%d-%d", r[0], r[1]))
+			}
+		}
+		args = append(args, strings.Join(ranges, ","))
+	}
+
+	output, err := exec.CommandContext(ctx, m.Binary, args...).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("erro ao executar mutool: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return collectImages(outputDir, "page_", ".png")
+}
+
+// collectImages lista as imagens geradas em outputDir, identificando o número de cada página pelo nome do arquivo.
+func collectImages(outputDir, prefix, suffix string) ([]Page, error) {
+	entries, err := os.ReadDir(outputDir)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar imagens geradas: %w", err)
+	}
+
+	var pages []Page
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, suffix) {
+			continue
+		}
+		page, err := pageNumber(name, prefix, suffix)
+		if err != nil {
+			return nil, fmt.Errorf("nome de imagem inesperado %s: %w", name, err)
+		}
+		pages = append(pages, Page{Number: page, Path: filepath.Join(outputDir, name)})
+	}
+
+	sortPages(pages)
+	return pages, nil
+}
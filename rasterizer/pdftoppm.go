@@ -0,0 +1,62 @@
+package rasterizer
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Pdftoppm rasteriza com o `pdftoppm` do Poppler.
+type Pdftoppm struct {
+	Binary string
+}
+
+func NewPdftoppm(binary string) *Pdftoppm {
+	if binary == "" {
+		binary = "pdftoppm"
+	}
+	return &Pdftoppm{Binary: binary}
+}
+
+func (p *Pdftoppm) Name() string {
+	return "pdftoppm"
+}
+
+func (p *Pdftoppm) Binaries() []string {
+	return []string{p.Binary}
+}
+
+func (p *Pdftoppm) Rasterize(ctx context.Context, pdfPath string, outputDir string, options Options) ([]Page, error) {
+	base := []string{"-png"}
+	if options.DPI > 0 {
+		base = append(base, "-r", strconv.Itoa(options.DPI))
+	}
+	if options.Grayscale {
+		base = append(base, "-gray")
+	}
+
+	// O pdftoppm aceita apenas um intervalo (-f/-l) por execução.
+	ranges := pageRanges(options.Pages)
+	if len(ranges) == 0 {
+		ranges = [][2]int{{0, 0}}
+	}
+
+	for _, r := range ranges {
+		args := append([]string(nil), base...)
+		if r[0] > 0 {
+			args = append(args, "-f", strconv.Itoa(r[0]), "-l", strconv.Itoa(r[1]))
+		}
+		args = append(args, pdfPath, filepath.Join(outputDir, "page"))
+
+		output, err := exec.CommandContext(ctx, p.Binary, args...).CombinedOutput()
+		if err != nil {
+			return nil, fmt.Errorf("erro ao executar pdftoppm: %w: %s", err, strings.TrimSpace(string(output)))
+		}
+	}
+
+	// O pdftoppm grava page-1.png, page-01.png ou page-001.png conforme o total de páginas.
+	return collectImages(outputDir, "page-", ".png")
+}
@@ -0,0 +1,170 @@
+package rasterizer
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Options controla a rasterização. DPI zerado usa o padrão do backend e Pages vazio rasteriza todas as páginas.
+type Options struct {
+	DPI       int   `json:"dpi,omitempty"`
+	Grayscale bool  `json:"grayscale,omitempty"`
+	Pages     []int `json:"pages,omitempty"`
+}
+
+// Page associa o número original da página à imagem gerada.
+type Page struct {
+	Number int    `json:"number"`
+	Path   string `json:"path"`
+}
+
+// Rasterizer converte páginas de um PDF em imagens gravadas em outputDir.
+// As páginas são devolvidas em ordem crescente de número.
+type Rasterizer interface {
+	Name() string
+	// Binaries lista os executáveis externos necessários; vazio para backends em Go puro.
+	Binaries() []string
+	Rasterize(ctx context.Context, pdfPath string, outputDir string, options Options) ([]Page, error)
+}
+
+// Validate verifica se as opções são aceitas pelos backends.
+func (o Options) Validate() error {
+	if o.DPI < 0 || o.DPI > 1200 {
+		return fmt.Errorf("dpi inválido: %d", o.DPI)
+	}
+	for _, page := range o.Pages {
+		if page < 1 {
+			return fmt.Errorf("página inválida: %d", page)
+		}
+	}
+	return nil
+}
+
+var (
+	mu          sync.RWMutex
+	rasterizers = map[string]Rasterizer{}
+)
+
+// Register disponibiliza um backend de rasterização pelo nome retornado em Name.
+func Register(rasterizer Rasterizer) {
+	mu.Lock()
+	defer mu.Unlock()
+	rasterizers[rasterizer.Name()] = rasterizer
+}
+
+// Get devolve o backend registrado com o nome informado.
+func Get(name string) (Rasterizer, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	rasterizer, ok := rasterizers[name]
+	if !ok {
+		return nil, fmt.Errorf("rasterizador desconhecido: %s", name)
+	}
+	return rasterizer, nil
+}
+
+// Capability informa se um executável externo foi encontrado no PATH.
+type Capability struct {
+	Binary    string `json:"binary"`
+	Path      string `json:"path,omitempty"`
+	Available bool   `json:"available"`
+}
+
+// CheckBinaries procura cada executável no PATH.
+func CheckBinaries(binaries ...string) []Capability {
+	capabilities := make([]Capability, 0, len(binaries))
+	for _, binary := range binaries {
+		path, err := exec.LookPath(binary)
+		capabilities = append(capabilities, Capability{Binary: binary, Path: path, Available: err == nil})
+	}
+	return capabilities
+}
+
+// Available devolve um erro descrevendo os executáveis ausentes exigidos pelo backend.
+func Available(rasterizer Rasterizer) error {
+	var missing []string
+	for _, capability := range CheckBinaries(rasterizer.Binaries()...) {
+		if !capability.Available {
+			missing = append(missing, capability.Binary)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("rasterizador %s requer %s, não encontrado(s) no PATH", rasterizer.Name(), strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// maxRangePages limita o tamanho dos intervalos aceitos por ParsePages.
+const maxRangePages = 10000
+
+// ParsePages lê uma seleção de páginas no formato "1-3,7" e devolve as páginas em ordem, sem repetições.
+// Uma seleção vazia devolve nil, que rasteriza todas as páginas.
+func ParsePages(value string) ([]int, error) {
+	selected := map[int]bool{}
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		first, last, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(strings.TrimSpace(first))
+		end := start
+		if err == nil && isRange {
+			end, err = strconv.Atoi(strings.TrimSpace(last))
+		}
+		if err != nil || start < 1 || end < start {
+			return nil, fmt.Errorf("intervalo de páginas inválido: %q", part)
+		}
+		if end-start >= maxRangePages {
+			return nil, fmt.Errorf("intervalo de páginas %q excede %d páginas", part, maxRangePages)
+		}
+		for page := start; page <= end; page++ {
+			selected[page] = true
+		}
+	}
+	if len(selected) == 0 {
+		return nil, nil
+	}
+
+	pages := make([]int, 0, len(selected))
+	for page := range selected {
+		pages = append(pages, page)
+	}
+	sort.Ints(pages)
+	return pages, nil
+}
+
+// pageRanges agrupa as páginas em intervalos contíguos, ex.: [1 2 3 7] -> [[1 3] [7 7]].
+func pageRanges(pages []int) [][2]int {
+	sorted := append([]int(nil), pages...)
+	sort.Ints(sorted)
+
+	var ranges [][2]int
+	for _, page := range sorted {
+		if n := len(ranges); n > 0 && page <= ranges[n-1][1]+1 {
+			if page > ranges[n-1][1] {
+				ranges[n-1][1] = page
+			}
+			continue
+		}
+		ranges = append(ranges, [2]int{page, page})
+	}
+	return ranges
+}
+
+// sortPages ordena as páginas pelo número, e não pelo nome do arquivo:
+// a ordem lexical colocaria page_10.png antes de page_2.png.
+func sortPages(pages []Page) {
+	sort.Slice(pages, func(i, j int) bool { return pages[i].Number < pages[j].Number })
+}
+
+// pageNumber extrai o número da página do nome de arquivo gerado, ex.: "page-007.png" -> 7.
+func pageNumber(name, prefix, suffix string) (int, error) {
+	return strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, prefix), suffix))
+}
@@ -0,0 +1,81 @@
+package rasterizer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCollectImagesOrdersByPageNumber(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		prefix string
+	}{
+		{name: "mutool", format: "page_%d.png", prefix: "page_"},
+		{name: "pdftoppm", format: "page-%02d.png", prefix: "page-"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			// Criados em ordem inversa para não depender da ordem de criação
+			for page := 11; page >= 1; page-- {
+				writeFile(t, filepath.Join(dir, fmt.Sprintf(tt.format, page)))
+			}
+			writeFile(t, filepath.Join(dir, "documento.pdf"))
+
+			pages, err := collectImages(dir, tt.prefix, ".png")
+			if err != nil {
+				t.Fatalf("collectImages: %v", err)
+			}
+			if len(pages) != 11 {
+				t.Fatalf("%d páginas, esperado 11", len(pages))
+			}
+			for i, page := range pages {
+				want := filepath.Join(dir, fmt.Sprintf(tt.format, i+1))
+				if page.Number != i+1 || page.Path != want {
+					t.Errorf("posição %d = página %d (%s), esperado página %d (%s)", i, page.Number, page.Path, i+1, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParsePages(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []int
+		err   bool
+	}{
+		{value: "", want: nil},
+		{value: "3", want: []int{3}},
+		{value: "1-3, 7", want: []int{1, 2, 3, 7}},
+		{value: "5-6,2,6", want: []int{2, 5, 6}},
+		{value: "0", err: true},
+		{value: "4-2", err: true},
+		{value: "a-b", err: true},
+		{value: "1-20000", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParsePages(tt.value)
+			if (err != nil) != tt.err {
+				t.Fatalf("erro = %v, esperado erro: %v", err, tt.err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParsePages(%q) = %v, esperado %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func writeFile(t *testing.T, path string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte("png"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -2,12 +2,29 @@ package router
 
 import (
 	"gosmart/handlers"
+	"gosmart/services"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 func SetupRoutes(app *fiber.App) {
+	app.Use(handlers.AuditRequests)
+	app.Use(handlers.RequireAPIKey)
+
+	app.Get("/health", handlers.HealthHandler)
 	app.Get("/example", handlers.ExampleHandler)
-	app.Post("/openai", handlers.OpenAIHandler)
-	app.Post("/process-pdf", handlers.ProcessPDFHandler)
+	app.Post("/openai", handlers.Idempotency, handlers.RateLimit(services.RateLimitOpenAI), handlers.OpenAIHandler)
+	app.Post("/process-pdf", handlers.Idempotency, handlers.RateLimit(services.RateLimitProcessPDF), handlers.ProcessPDFHandler)
+	app.Post("/jobs", handlers.Idempotency, handlers.RateLimit(services.RateLimitProcessPDF), handlers.CreateJobHandler)
+	app.Get("/jobs/:id", handlers.GetJobHandler)
+	app.Delete("/jobs/:id", handlers.CancelJobHandler)
+	app.Get("/queue", handlers.QueueStatsHandler)
+	app.Get("/schemas", handlers.ListSchemasHandler)
+	app.Get("/usage", handlers.RequireAdmin, handlers.UsageReportHandler)
+	app.Get("/usage/documents/:id", handlers.RequireAdmin, handlers.DocumentUsageHandler)
+
+	admin := app.Group("/admin", handlers.RequireAdmin)
+	admin.Get("/budgets", handlers.BudgetStatusHandler)
+	admin.Post("/budgets/raise", handlers.RaiseBudgetHandler)
+	app.Get("/audit", handlers.RequireAdmin, handlers.AuditHandler)
 }
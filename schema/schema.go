@@ -0,0 +1,227 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// Tipos aceitos nos campos, os mesmos do JSON Schema.
+const (
+	TypeString  = "string"
+	TypeNumber  = "number"
+	TypeInteger = "integer"
+	TypeBoolean = "boolean"
+	TypeObject  = "object"
+	TypeArray   = "array"
+)
+
+// Field descreve um campo do resultado. Objetos declaram seus campos em Fields e listas o tipo dos itens em Items.
+type Field struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	Description string   `json:"description,omitempty"`
+	Required    bool     `json:"required,omitempty"`
+	Enum        []string `json:"enum,omitempty"`
+	Fields      []Field  `json:"fields,omitempty"`
+	Items       *Field   `json:"items,omitempty"`
+}
+
+// Schema é um esquema de extração nomeado: define as chaves, os tipos e os valores aceitos
+// no JSON devolvido pelo modelo, para que todos os documentos usem os mesmos nomes de campo.
+type Schema struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description,omitempty"`
+	Fields      []Field `json:"fields"`
+}
+
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Check verifica se a definição do esquema é válida.
+func (s *Schema) Check() error {
+	if !namePattern.MatchString(s.Name) {
+		return fmt.Errorf("nome de esquema inválido: %q", s.Name)
+	}
+	if len(s.Fields) == 0 {
+		return fmt.Errorf("esquema %s sem campos", s.Name)
+	}
+	return checkFields(s.Name, s.Fields)
+}
+
+func checkFields(path string, fields []Field) error {
+	seen := map[string]bool{}
+	for _, field := range fields {
+		if field.Name == "" {
+			return fmt.Errorf("%s: campo sem nome", path)
+		}
+		if seen[field.Name] {
+			return fmt.Errorf("%s: campo %s duplicado", path, field.Name)
+		}
+		seen[field.Name] = true
+		if err := checkField(path+"."+field.Name, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkField(path string, field Field) error {
+	if len(field.Enum) > 0 && field.Type != TypeString {
+		return fmt.Errorf("%s: enum só é aceito em campos string", path)
+	}
+
+	switch field.Type {
+	case TypeString, TypeNumber, TypeInteger, TypeBoolean:
+		return nil
+	case TypeObject:
+		if len(field.Fields) == 0 {
+			return fmt.Errorf("%s: objeto sem campos", path)
+		}
+		return checkFields(path, field.Fields)
+	case TypeArray:
+		if field.Items == nil {
+			return fmt.Errorf("%s: lista sem o tipo dos itens", path)
+		}
+		return checkField(path+"[]", *field.Items)
+	default:
+		return fmt.Errorf("%s: tipo %q não suportado", path, field.Type)
+	}
+}
+
+// JSONSchema converte o esquema para JSON Schema. No modo strict (exigido pelo response_format
+// json_schema da OpenAI) todos os campos são listados como obrigatórios e os opcionais aceitam null.
+func (s *Schema) JSONSchema(strict bool) map[string]interface{} {
+	return objectSchema(s.Fields, strict)
+}
+
+func objectSchema(fields []Field, strict bool) map[string]interface{} {
+	properties := map[string]interface{}{}
+	required := []string{}
+	for _, field := range fields {
+		properties[field.Name] = fieldSchema(field, strict)
+		if field.Required || strict {
+			required = append(required, field.Name)
+		}
+	}
+
+	return map[string]interface{}{
+		"type":                 TypeObject,
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+func fieldSchema(field Field, strict bool) map[string]interface{} {
+	var out map[string]interface{}
+	switch field.Type {
+	case TypeObject:
+		out = objectSchema(field.Fields, strict)
+	case TypeArray:
+		// Itens de lista nunca são nulos; a opcionalidade vale apenas para campos de objeto
+		items := *field.Items
+		items.Required = true
+		out = map[string]interface{}{"type": TypeArray, "items": fieldSchema(items, strict)}
+	default:
+		out = map[string]interface{}{"type": field.Type}
+	}
+
+	if field.Description != "" {
+		out["description"] = field.Description
+	}
+	if len(field.Enum) > 0 {
+		enum := make([]interface{}, 0, len(field.Enum)+1)
+		for _, value := range field.Enum {
+			enum = append(enum, value)
+		}
+		if strict && !field.Required {
+			enum = append(enum, nil)
+		}
+		out["enum"] = enum
+	}
+	if strict && !field.Required {
+		out["type"] = []interface{}{out["type"], "null"}
+	}
+	return out
+}
+
+var (
+	mu      sync.RWMutex
+	schemas = map[string]*Schema{}
+)
+
+// Register disponibiliza um esquema pelo nome, substituindo um esquema anterior com o mesmo nome.
+func Register(s *Schema) error {
+	if err := s.Check(); err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	schemas[s.Name] = s
+	return nil
+}
+
+// Get devolve o esquema registrado com o nome informado.
+func Get(name string) (*Schema, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	s, ok := schemas[name]
+	if !ok {
+		return nil, fmt.Errorf("esquema de extração desconhecido: %s", name)
+	}
+	return s, nil
+}
+
+// List devolve os esquemas registrados, ordenados pelo nome.
+func List() []*Schema {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	list := make([]*Schema, 0, len(schemas))
+	for _, s := range schemas {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Parse lê a definição de um esquema em JSON e verifica se é válida.
+func Parse(data []byte) (*Schema, error) {
+	var s Schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("definição de esquema inválida: %w", err)
+	}
+	if err := s.Check(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// LoadDir registra todos os arquivos *.json do diretório como esquemas.
+func LoadDir(dir string) (int, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return 0, fmt.Errorf("erro ao ler esquema %s: %w", path, err)
+		}
+		s, err := Parse(data)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", path, err)
+		}
+		if err := Register(s); err != nil {
+			return 0, err
+		}
+	}
+	return len(paths), nil
+}
@@ -0,0 +1,118 @@
+package schema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema Schema
+		err    string
+	}{
+		{
+			name: "válido",
+			schema: Schema{Name: "pedido", Fields: []Field{
+				{Name: "numero", Type: TypeString, Required: true},
+				{Name: "itens", Type: TypeArray, Items: &Field{Type: TypeObject, Fields: []Field{{Name: "preco", Type: TypeNumber}}}},
+			}},
+		},
+		{name: "nome inválido", schema: Schema{Name: "meu esquema", Fields: []Field{{Name: "a", Type: TypeString}}}, err: "nome de esquema inválido"},
+		{name: "sem campos", schema: Schema{Name: "vazio"}, err: "sem campos"},
+		{name: "campo duplicado", schema: Schema{Name: "s", Fields: []Field{{Name: "a", Type: TypeString}, {Name: "a", Type: TypeNumber}}}, err: "s: campo a duplicado"},
+		{name: "enum em número", schema: Schema{Name: "s", Fields: []Field{{Name: "a", Type: TypeNumber, Enum: []string{"1"}}}}, err: "s.a: enum só é aceito em campos string"},
+		{name: "objeto sem campos", schema: Schema{Name: "s", Fields: []Field{{Name: "a", Type: TypeObject}}}, err: "s.a: objeto sem campos"},
+		{name: "lista sem itens", schema: Schema{Name: "s", Fields: []Field{{Name: "a", Type: TypeArray}}}, err: "s.a: lista sem o tipo dos itens"},
+		{name: "tipo de item inválido", schema: Schema{Name: "s", Fields: []Field{{Name: "a", Type: TypeArray, Items: &Field{Type: "date"}}}}, err: `s.a[]: tipo "date" não suportado`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.schema.Check()
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("erro inesperado: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("erro = %v, esperado %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	s := &Schema{Name: "pedido", Fields: []Field{
+		{Name: "numero", Type: TypeString, Required: true},
+		{Name: "status", Type: TypeString, Enum: []string{"aberto", "fechado"}},
+		{Name: "itens", Type: TypeArray, Items: &Field{Type: TypeObject, Fields: []Field{
+			{Name: "codigo", Type: TypeString, Required: true, Description: "Código do produto"},
+			{Name: "quantidade", Type: TypeInteger},
+		}}},
+	}}
+
+	tests := []struct {
+		name   string
+		strict bool
+		want   string
+	}{
+		{
+			name:   "tools",
+			strict: false,
+			want: `{
+				"type": "object", "additionalProperties": false, "required": ["numero"],
+				"properties": {
+					"numero": {"type": "string"},
+					"status": {"type": "string", "enum": ["aberto", "fechado"]},
+					"itens": {"type": "array", "items": {
+						"type": "object", "additionalProperties": false, "required": ["codigo"],
+						"properties": {
+							"codigo": {"type": "string", "description": "Código do produto"},
+							"quantidade": {"type": "integer"}
+						}
+					}}
+				}
+			}`,
+		},
+		{
+			name:   "strict",
+			strict: true,
+			want: `{
+				"type": "object", "additionalProperties": false, "required": ["numero", "status", "itens"],
+				"properties": {
+					"numero": {"type": "string"},
+					"status": {"type": ["string", "null"], "enum": ["aberto", "fechado", null]},
+					"itens": {"type": ["array", "null"], "items": {
+						"type": "object", "additionalProperties": false, "required": ["codigo", "quantidade"],
+						"properties": {
+							"codigo": {"type": "string", "description": "Código do produto"},
+							"quantidade": {"type": ["integer", "null"]}
+						}
+					}}
+				}
+			}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(s.JSONSchema(tt.strict))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var gotValue, wantValue interface{}
+			json.Unmarshal(got, &gotValue)
+			if err := json.Unmarshal([]byte(tt.want), &wantValue); err != nil {
+				t.Fatal(err)
+			}
+			gotJSON, _ := json.Marshal(gotValue)
+			wantJSON, _ := json.Marshal(wantValue)
+			if string(gotJSON) != string(wantJSON) {
+				t.Errorf("JSONSchema = %s\nesperado %s", gotJSON, wantJSON)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package schema
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Validate confere o JSON decodificado contra o esquema e devolve uma mensagem por violação,
+// com o caminho do campo (ex.: produtos[3].preco). Uma lista vazia indica que os dados estão conformes.
+func (s *Schema) Validate(data interface{}) []string {
+	var errs []string
+	validateObject("$", s.Fields, data, &errs)
+	return errs
+}
+
+func validateObject(path string, fields []Field, value interface{}, errs *[]string) {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		*errs = append(*errs, fmt.Sprintf("%s: esperado objeto, recebido %s", path, typeName(value)))
+		return
+	}
+
+	known := map[string]bool{}
+	for _, field := range fields {
+		known[field.Name] = true
+		fieldValue, present := object[field.Name]
+		if !present || fieldValue == nil {
+			if field.Required {
+				*errs = append(*errs, fmt.Sprintf("%s.%s: campo obrigatório ausente", path, field.Name))
+			}
+			continue
+		}
+		validateField(path+"."+field.Name, field, fieldValue, errs)
+	}
+
+	var unknown []string
+	for key := range object {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		*errs = append(*errs, fmt.Sprintf("%s.%s: campo não previsto no esquema", path, key))
+	}
+}
+
+func validateField(path string, field Field, value interface{}, errs *[]string) {
+	switch field.Type {
+	case TypeString:
+		text, ok := value.(string)
+		if !ok {
+			*errs = append(*errs, fmt.Sprintf("%s: esperado string, recebido %s", path, typeName(value)))
+			return
+		}
+		if len(field.Enum) > 0 && !contains(field.Enum, text) {
+			*errs = append(*errs, fmt.Sprintf("%s: valor %q fora dos permitidos %v", path, text, field.Enum))
+		}
+	case TypeNumber:
+		if _, ok := value.(float64); !ok {
+			*errs = append(*errs, fmt.Sprintf("%s: esperado number, recebido %s", path, typeName(value)))
+		}
+	case TypeInteger:
+		number, ok := value.(float64)
+		if !ok || number != math.Trunc(number) {
+			*errs = append(*errs, fmt.Sprintf("%s: esperado integer, recebido %s", path, typeName(value)))
+		}
+	case TypeBoolean:
+		if _, ok := value.(bool); !ok {
+			*errs = append(*errs, fmt.Sprintf("%s: esperado boolean, recebido %s", path, typeName(value)))
+		}
+	case TypeObject:
+		validateObject(path, field.Fields, value, errs)
+	case TypeArray:
+		items, ok := value.([]interface{})
+		if !ok {
+			*errs = append(*errs, fmt.Sprintf("%s: esperado array, recebido %s", path, typeName(value)))
+			return
+		}
+		for i, item := range items {
+			itemPath := fmt.Sprintf("%s[%d]", path, i)
+			if item == nil {
+				*errs = append(*errs, itemPath+": item nulo")
+				continue
+			}
+			validateField(itemPath, *field.Items, item, errs)
+		}
+	}
+}
+
+func typeName(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case bool:
+		return "boolean"
+	case map[string]interface{}:
+		return "objeto"
+	case []interface{}:
+		return "array"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+var pedido = &Schema{Name: "pedido", Fields: []Field{
+	{Name: "numero", Type: TypeString, Required: true},
+	{Name: "status", Type: TypeString, Enum: []string{"aberto", "fechado"}},
+	{Name: "urgente", Type: TypeBoolean},
+	{Name: "itens", Type: TypeArray, Required: true, Items: &Field{Type: TypeObject, Fields: []Field{
+		{Name: "codigo", Type: TypeString, Required: true},
+		{Name: "quantidade", Type: TypeInteger},
+		{Name: "preco", Type: TypeNumber},
+		{Name: "tags", Type: TypeArray, Items: &Field{Type: TypeString}},
+	}}},
+}}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{
+			name: "conforme",
+			data: `{"numero": "10", "status": "aberto", "urgente": false, "itens": [{"codigo": "1", "quantidade": 2, "preco": 1.5, "tags": ["a"]}]}`,
+		},
+		{
+			name: "opcionais nulos",
+			data: `{"numero": "10", "status": null, "itens": []}`,
+		},
+		{
+			name: "obrigatórios ausentes",
+			data: `{"status": "aberto"}`,
+			want: []string{"$.numero: campo obrigatório ausente", "$.itens: campo obrigatório ausente"},
+		},
+		{
+			name: "tipos errados",
+			data: `{"numero": 10, "urgente": "sim", "itens": {}}`,
+			want: []string{
+				"$.numero: esperado string, recebido integer",
+				"$.urgente: esperado boolean, recebido string",
+				"$.itens: esperado array, recebido objeto",
+			},
+		},
+		{
+			name: "valor fora do enum",
+			data: `{"numero": "10", "status": "cancelado", "itens": []}`,
+			want: []string{`$.status: valor "cancelado" fora dos permitidos [aberto fechado]`},
+		},
+		{
+			name: "listas aninhadas",
+			data: `{"numero": "10", "itens": [{"codigo": "1", "quantidade": 1.5}, null, {"preco": "2,50", "tags": ["a", 3]}]}`,
+			want: []string{
+				"$.itens[0].quantidade: esperado integer, recebido number",
+				"$.itens[1]: item nulo",
+				"$.itens[2].codigo: campo obrigatório ausente",
+				"$.itens[2].preco: esperado number, recebido string",
+				"$.itens[2].tags[1]: esperado string, recebido integer",
+			},
+		},
+		{
+			name: "campos não previstos",
+			data: `{"numero": "10", "itens": [{"codigo": "1", "cor": "azul"}], "total": 5, "obs": ""}`,
+			want: []string{"$.itens[0].cor: campo não previsto no esquema", "$.obs: campo não previsto no esquema", "$.total: campo não previsto no esquema"},
+		},
+		{
+			name: "raiz não é objeto",
+			data: `[1, 2]`,
+			want: []string{"$: esperado objeto, recebido array"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data interface{}
+			if err := json.Unmarshal([]byte(tt.data), &data); err != nil {
+				t.Fatal(err)
+			}
+			if got := pedido.Validate(data); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate = %q, esperado %q", got, tt.want)
+			}
+		})
+	}
+}
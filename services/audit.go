@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"github.com/gofiber/fiber/v2/log"
+	"gosmart/entities"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	auditStreamKey         = "gosmart:audit"
+	defaultAuditMaxLen     = 100000
+	auditScanBatch         = 500
+	DefaultAuditQueryLimit = 100
+	MaxAuditQueryLimit     = 1000
+)
+
+// AuditLog grava eventos estruturados em um Redis Stream. O tamanho do stream é limitado por
+// AUDIT_MAX_LEN e, se configurada, AUDIT_RETENTION descarta eventos mais antigos que o prazo.
+// Os IDs do stream são o instante da gravação, então consultas por período usam XRANGE diretamente.
+type AuditLog struct {
+	maxLen    int64
+	retention time.Duration
+}
+
+var Audit *AuditLog
+
+// InitAudit lê AUDIT_MAX_LEN e AUDIT_RETENTION.
+func InitAudit() {
+	Audit = &AuditLog{
+		maxLen:    int64(limitFromEnv("AUDIT_MAX_LEN", defaultAuditMaxLen)),
+		retention: durationFromEnv("AUDIT_RETENTION", 0),
+	}
+}
+
+// Record acrescenta o evento ao stream. Falhas no Redis são registradas no log e não interrompem a requisição;
+// com o Redis fora do ar, o evento é descartado.
+func (a *AuditLog) Record(ctx context.Context, event entities.AuditEvent) {
+	if !RedisAvailable() {
+		return
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Error("erro ao serializar evento de auditoria: ", err)
+		return
+	}
+
+	_, err = RedisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: auditStreamKey,
+			MaxLen: a.maxLen,
+			Approx: true,
+			Values: map[string]interface{}{"event": data},
+		})
+		if a.retention > 0 {
+			minID := strconv.FormatInt(time.Now().Add(-a.retention).UnixMilli(), 10)
+			pipe.XTrimMinIDApprox(ctx, auditStreamKey, minID, 0)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Error("erro ao gravar evento de auditoria: ", err)
+	}
+}
+
+// AuditQuery filtra os eventos de GET /audit. Caller e Status vazios não filtram; Status aceita
+// o valor exato ou a classe do status HTTP (ex.: 5xx). After é o cursor devolvido em AuditPage.Next.
+type AuditQuery struct {
+	From   time.Time
+	To     time.Time
+	Caller string
+	Status string
+	Limit  int
+	After  string
+}
+
+// Query devolve os eventos do período em ordem cronológica, até Limit por página.
+func (a *AuditLog) Query(ctx context.Context, query AuditQuery) (*entities.AuditPage, error) {
+	start := strconv.FormatInt(query.From.UnixMilli(), 10)
+	if query.After != "" {
+		// "(" torna o início exclusivo, para não repetir o último evento da página anterior
+		start = "(" + query.After
+	}
+	end := strconv.FormatInt(query.To.UnixMilli(), 10)
+
+	page := &entities.AuditPage{Events: []entities.AuditEvent{}}
+	for {
+		messages, err := RedisClient.XRangeN(ctx, auditStreamKey, start, end, auditScanBatch).Result()
+		if err != nil {
+			return nil, fmt.Errorf("erro ao ler log de auditoria: %w", err)
+		}
+
+		for _, message := range messages {
+			event, err := auditEventFromMessage(message)
+			if err != nil {
+				log.Warnf("evento de auditoria %s ignorado: %v", message.ID, err)
+				continue
+			}
+			if !query.matches(event) {
+				continue
+			}
+			page.Events = append(page.Events, event)
+			if len(page.Events) == query.Limit {
+				page.Next = message.ID
+				return page, nil
+			}
+		}
+
+		if len(messages) < auditScanBatch {
+			return page, nil
+		}
+		start = "(" + messages[len(messages)-1].ID
+	}
+}
+
+func (q AuditQuery) matches(event entities.AuditEvent) bool {
+	if q.Caller != "" && event.Caller != q.Caller {
+		return false
+	}
+	if q.Status == "" || event.Status == q.Status {
+		return true
+	}
+	// Classe do status HTTP: "5xx" casa com 500, 502...
+	return len(q.Status) == 3 && strings.HasSuffix(strings.ToLower(q.Status), "xx") &&
+		len(event.Status) == 3 && event.Status[0] == q.Status[0]
+}
+
+func auditEventFromMessage(message redis.XMessage) (entities.AuditEvent, error) {
+	var event entities.AuditEvent
+	data, ok := message.Values["event"].(string)
+	if !ok {
+		return event, fmt.Errorf("campo event ausente")
+	}
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		return event, err
+	}
+	event.ID = message.ID
+	return event, nil
+}
+
+type auditEventKey struct{}
+
+// auditRecorder acumula no contexto da requisição os dados do evento preenchidos pelos handlers e serviços.
+type auditRecorder struct {
+	mu    sync.Mutex
+	event *entities.AuditEvent
+}
+
+// WithAuditEvent associa o evento ao contexto, para que handlers e serviços o completem com AnnotateAudit.
+func WithAuditEvent(ctx context.Context, event *entities.AuditEvent) context.Context {
+	return context.WithValue(ctx, auditEventKey{}, &auditRecorder{event: event})
+}
+
+// AnnotateAudit altera o evento de auditoria do contexto, se houver.
+func AnnotateAudit(ctx context.Context, annotate func(event *entities.AuditEvent)) {
+	recorder, ok := ctx.Value(auditEventKey{}).(*auditRecorder)
+	if !ok {
+		return
+	}
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	annotate(recorder.event)
+}
+
+// auditUsage soma o consumo de uma chamada ao LLM ao evento de auditoria do contexto.
+func auditUsage(ctx context.Context, model string, usage entities.TokenUsage) {
+	AnnotateAudit(ctx, func(event *entities.AuditEvent) {
+		event.Model = model
+		event.PromptTokens += int64(usage.PromptTokens)
+		event.CompletionTokens += int64(usage.CompletionTokens)
+		event.TotalTokens += int64(usage.TotalTokens)
+	})
+}
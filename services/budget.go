@@ -0,0 +1,335 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"github.com/gofiber/fiber/v2/log"
+	"gosmart/config"
+	"gosmart/entities"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	BudgetPeriodDaily   = "daily"
+	BudgetPeriodMonthly = "monthly"
+	BudgetScopeGlobal   = "global"
+
+	budgetRaiseKeyPrefix   = "gosmart:budget:raise:"
+	defaultBudgetWarnRatio = 0.8
+)
+
+var ErrBudgetExceeded = errors.New("orçamento de consumo do LLM esgotado")
+
+// BudgetExceededError informa qual teto foi atingido.
+type BudgetExceededError struct {
+	Scope    string
+	Period   string
+	SpentUSD float64
+	LimitUSD float64
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("%s: gasto %s de %s em US$ %.2f, limite de US$ %.2f", ErrBudgetExceeded, budgetPeriodName(e.Period), e.Scope, e.SpentUSD, e.LimitUSD)
+}
+
+func (e *BudgetExceededError) Is(target error) bool {
+	return target == ErrBudgetExceeded
+}
+
+// BudgetLimits são os tetos de gasto em dólares; zero significa sem limite.
+type BudgetLimits struct {
+	Daily   float64
+	Monthly float64
+}
+
+func (l BudgetLimits) get(period string) float64 {
+	if period == BudgetPeriodDaily {
+		return l.Daily
+	}
+	return l.Monthly
+}
+
+// BudgetManager aplica tetos diários e mensais de gasto com o LLM, globais e por chamador, sobre os
+// agregados gravados pelo UsageTracker. A verificação é feita antes de cada chamada, então chamadas
+// simultâneas podem ultrapassar o teto pelo custo das que já estavam em andamento.
+type BudgetManager struct {
+	global        BudgetLimits
+	callerDefault BudgetLimits
+	callers       map[string]BudgetLimits
+	warnRatio     float64
+	warned        sync.Map
+}
+
+var Budgets *BudgetManager
+
+// InitBudgets carrega os tetos de gasto do ambiente. Deve ser chamado depois de InitUsage.
+func InitBudgets() {
+	Budgets = &BudgetManager{
+		global: BudgetLimits{
+			Daily:   floatFromEnv("BUDGET_DAILY_USD"),
+			Monthly: floatFromEnv("BUDGET_MONTHLY_USD"),
+		},
+		callerDefault: BudgetLimits{
+			Daily:   floatFromEnv("BUDGET_CALLER_DAILY_USD"),
+			Monthly: floatFromEnv("BUDGET_CALLER_MONTHLY_USD"),
+		},
+		callers:   parseBudgetLimits(config.GetEnv("BUDGET_CALLER_LIMITS")),
+		warnRatio: defaultBudgetWarnRatio,
+	}
+
+	if ratio := floatFromEnv("BUDGET_WARN_RATIO"); ratio > 0 && ratio <= 1 {
+		Budgets.warnRatio = ratio
+	} else if ratio != 0 {
+		log.Warnf("BUDGET_WARN_RATIO deve estar entre 0 e 1, usando %.2f", defaultBudgetWarnRatio)
+	}
+}
+
+func floatFromEnv(key string) float64 {
+	value := config.GetEnv(key)
+	if value == "" {
+		return 0
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil || parsed < 0 {
+		log.Warnf("%s inválido (%s), ignorando", key, value)
+		return 0
+	}
+	return parsed
+}
+
+// parseBudgetLimits lê tetos por chamador no formato "nome=diario/mensal"; um dos lados pode ficar vazio.
+func parseBudgetLimits(value string) map[string]BudgetLimits {
+	limits := map[string]BudgetLimits{}
+	for _, entry := range splitList(value) {
+		name, amounts, ok := strings.Cut(entry, "=")
+		daily, monthly, okAmounts := strings.Cut(amounts, "/")
+		parsed := BudgetLimits{}
+		var errDaily, errMonthly error
+		if daily = strings.TrimSpace(daily); daily != "" {
+			parsed.Daily, errDaily = strconv.ParseFloat(daily, 64)
+		}
+		if monthly = strings.TrimSpace(monthly); monthly != "" {
+			parsed.Monthly, errMonthly = strconv.ParseFloat(monthly, 64)
+		}
+		if !ok || !okAmounts || errDaily != nil || errMonthly != nil || parsed.Daily < 0 || parsed.Monthly < 0 {
+			log.Warnf("entrada inválida em BUDGET_CALLER_LIMITS: %s", entry)
+			continue
+		}
+		limits[strings.TrimSpace(name)] = parsed
+	}
+	return limits
+}
+
+// limits devolve os tetos do escopo: os globais, os de BUDGET_CALLER_LIMITS ou, para AnonymousCaller e os
+// demais nomes de API_KEYS, o padrão por chamador. Escopos desconhecidos não têm teto próprio.
+func (b *BudgetManager) limits(scope string) BudgetLimits {
+	if scope == BudgetScopeGlobal {
+		return b.global
+	}
+	if limits, ok := b.callers[scope]; ok {
+		return limits
+	}
+	if KnownCaller(scope) {
+		return b.callerDefault
+	}
+	return BudgetLimits{}
+}
+
+// Check recusa a chamada quando o gasto do período já atingiu o teto global ou o do chamador do contexto.
+// Se o Redis estiver indisponível, a chamada é liberada e o processamento continua.
+func (b *BudgetManager) Check(ctx context.Context) error {
+	if !RedisAvailable() {
+		return nil
+	}
+
+	statuses, err := b.Status(ctx, scopeFromContext(ctx).Caller)
+	if err != nil {
+		log.Error("erro ao verificar orçamento de consumo, chamada liberada: ", err)
+		return nil
+	}
+
+	for _, status := range statuses {
+		if status.Exceeded {
+			return &BudgetExceededError{Scope: status.Scope, Period: status.Period, SpentUSD: status.SpentUSD, LimitUSD: status.LimitUSD}
+		}
+		if status.Warning {
+			key := status.Scope + ":" + status.Period + ":" + status.PeriodID
+			if _, warned := b.warned.LoadOrStore(key, true); !warned {
+				log.Warnf("gasto %s de %s em US$ %.2f, %.0f%% do limite de US$ %.2f", budgetPeriodName(status.Period), status.Scope, status.SpentUSD, 100*status.SpentUSD/status.LimitUSD, status.LimitUSD)
+			}
+		}
+	}
+	return nil
+}
+
+// Warnings devolve avisos para a resposta quando o gasto do chamador do contexto, ou o global,
+// passou do limiar BUDGET_WARN_RATIO do teto.
+func (b *BudgetManager) Warnings(ctx context.Context) []string {
+	if !RedisAvailable() {
+		return nil
+	}
+
+	statuses, err := b.Status(ctx, scopeFromContext(ctx).Caller)
+	if err != nil {
+		return nil
+	}
+
+	var warnings []string
+	for _, status := range statuses {
+		if status.Warning {
+			warnings = append(warnings, fmt.Sprintf("O gasto %s de %s com o LLM está em %.0f%% do limite de US$ %.2f", budgetPeriodName(status.Period), status.Scope, 100*status.SpentUSD/status.LimitUSD, status.LimitUSD))
+		}
+	}
+	return warnings
+}
+
+// Status devolve a situação dos tetos configurados no período corrente, globais e, se informado, do chamador.
+func (b *BudgetManager) Status(ctx context.Context, caller string) ([]entities.BudgetStatus, error) {
+	scopes := []string{BudgetScopeGlobal}
+	if caller != "" {
+		scopes = append(scopes, caller)
+	}
+
+	type pending struct {
+		status entities.BudgetStatus
+		spent  *redis.StringCmd
+		raise  *redis.StringCmd
+		ttl    *redis.DurationCmd
+	}
+	var checks []*pending
+
+	now := time.Now().UTC()
+	_, err := RedisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, scope := range scopes {
+			for _, period := range []string{BudgetPeriodDaily, BudgetPeriodMonthly} {
+				periodID := budgetPeriodID(period, now)
+				status := entities.BudgetStatus{Scope: scope, Period: period, PeriodID: periodID, BaseLimitUSD: b.limits(scope).get(period)}
+				if status.BaseLimitUSD == 0 {
+					continue
+				}
+
+				usageCaller := scope
+				if scope == BudgetScopeGlobal {
+					usageCaller = ""
+				}
+				usagePeriod := "day"
+				if period == BudgetPeriodMonthly {
+					usagePeriod = "month"
+				}
+
+				raiseKey := budgetRaiseKey(scope, period, periodID)
+				checks = append(checks, &pending{
+					status: status,
+					spent:  pipe.HGet(ctx, usageKey(usagePeriod, periodID, usageCaller), "cost_micros"),
+					raise:  pipe.Get(ctx, raiseKey),
+					ttl:    pipe.TTL(ctx, raiseKey),
+				})
+			}
+		}
+		return nil
+	})
+	// Campos e chaves ausentes (redis.Nil) significam gasto ou aumento zero
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("erro ao ler orçamento no Redis: %w", err)
+	}
+
+	statuses := make([]entities.BudgetStatus, 0, len(checks))
+	for _, check := range checks {
+		for _, cmd := range []redis.Cmder{check.spent, check.raise, check.ttl} {
+			if err := cmd.Err(); err != nil && !errors.Is(err, redis.Nil) {
+				return nil, fmt.Errorf("erro ao ler orçamento no Redis: %w", err)
+			}
+		}
+
+		status := check.status
+		spent, _ := check.spent.Int64()
+		raise, _ := check.raise.Int64()
+		status.SpentUSD = float64(spent) / microsPerUSD
+		status.RaiseUSD = float64(raise) / microsPerUSD
+		status.LimitUSD = status.BaseLimitUSD + status.RaiseUSD
+		if raise > 0 && check.ttl.Val() > 0 {
+			expires := now.Add(check.ttl.Val()).Truncate(time.Second)
+			status.RaiseExpiresAt = &expires
+		}
+		status.Exceeded = status.SpentUSD >= status.LimitUSD
+		status.Warning = status.SpentUSD >= status.LimitUSD*b.warnRatio
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Raise aumenta temporariamente o teto de um escopo no período corrente, substituindo um aumento anterior.
+// Sem duração, o aumento vale até o fim do período.
+func (b *BudgetManager) Raise(ctx context.Context, scope string, period string, amountUSD float64, duration time.Duration) (*entities.BudgetStatus, error) {
+	if period != BudgetPeriodDaily && period != BudgetPeriodMonthly {
+		return nil, fmt.Errorf("período inválido: %s", period)
+	}
+	if amountUSD <= 0 {
+		return nil, errors.New("o aumento deve ser maior que zero")
+	}
+	if b.limits(scope).get(period) == 0 {
+		return nil, fmt.Errorf("%s não tem limite %s configurado", scope, budgetPeriodName(period))
+	}
+
+	now := time.Now().UTC()
+	periodEnd := budgetPeriodEnd(period, now)
+	if duration <= 0 || now.Add(duration).After(periodEnd) {
+		duration = periodEnd.Sub(now)
+	}
+
+	key := budgetRaiseKey(scope, period, budgetPeriodID(period, now))
+	amount := int64(math.Round(amountUSD * microsPerUSD))
+	if err := RedisClient.Set(ctx, key, amount, duration).Err(); err != nil {
+		return nil, fmt.Errorf("erro ao gravar aumento de orçamento: %w", err)
+	}
+	log.Infof("limite %s de %s aumentado em US$ %.2f por %s", budgetPeriodName(period), scope, amountUSD, duration.Truncate(time.Second))
+
+	caller := scope
+	if scope == BudgetScopeGlobal {
+		caller = ""
+	}
+	statuses, err := b.Status(ctx, caller)
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range statuses {
+		if status.Scope == scope && status.Period == period {
+			return &status, nil
+		}
+	}
+	return nil, fmt.Errorf("limite %s de %s não encontrado", budgetPeriodName(period), scope)
+}
+
+func budgetRaiseKey(scope string, period string, periodID string) string {
+	if scope != BudgetScopeGlobal {
+		scope = "caller:" + scope
+	}
+	return budgetRaiseKeyPrefix + scope + ":" + period + ":" + periodID
+}
+
+func budgetPeriodID(period string, now time.Time) string {
+	if period == BudgetPeriodDaily {
+		return now.Format(usageDateLayout)
+	}
+	return now.Format(usageMonthLayout)
+}
+
+func budgetPeriodEnd(period string, now time.Time) time.Time {
+	if period == BudgetPeriodDaily {
+		return time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func budgetPeriodName(period string) string {
+	if period == BudgetPeriodDaily {
+		return "diário"
+	}
+	return "mensal"
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func testBudgets() *BudgetManager {
+	return &BudgetManager{
+		global:        BudgetLimits{Daily: 100, Monthly: 1000},
+		callerDefault: BudgetLimits{Daily: 1, Monthly: 10},
+		callers:       map[string]BudgetLimits{"erp": {Daily: 5}},
+		warnRatio:     defaultBudgetWarnRatio,
+	}
+}
+
+func TestBudgetLimits(t *testing.T) {
+	useAPIKeys(t, map[string]string{"chave-erp": "erp", "chave-site": "site"})
+	budgets := testBudgets()
+
+	tests := []struct {
+		name  string
+		scope string
+		want  BudgetLimits
+	}{
+		{name: "global", scope: BudgetScopeGlobal, want: BudgetLimits{Daily: 100, Monthly: 1000}},
+		{name: "chamador com teto próprio", scope: "erp", want: BudgetLimits{Daily: 5}},
+		{name: "chamador configurado sem teto próprio", scope: "site", want: BudgetLimits{Daily: 1, Monthly: 10}},
+		{name: "anônimo", scope: AnonymousCaller, want: BudgetLimits{Daily: 1, Monthly: 10}},
+		{name: "chamador desconhecido", scope: "key_0123456789ab", want: BudgetLimits{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := budgets.limits(tt.scope); got != tt.want {
+				t.Errorf("limits(%q) = %+v, esperado %+v", tt.scope, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBudgetCheckWithoutRedis(t *testing.T) {
+	previous := redisUp.Load()
+	redisUp.Store(false)
+	t.Cleanup(func() { redisUp.Store(previous) })
+
+	if err := testBudgets().Check(context.Background()); err != nil {
+		t.Errorf("Check sem Redis = %v, esperado nil", err)
+	}
+}
+
+func TestBudgetCheckSharesUnknownCallers(t *testing.T) {
+	useTestRedis(t)
+	useAPIKeys(t, map[string]string{"chave-erp": "erp"})
+	budgets := testBudgets()
+	budgets.global = BudgetLimits{}
+
+	// Soma US$ 2 ao gasto do dia do chamador anônimo, acima do teto padrão de US$ 1, e desfaz no fim
+	ctx := context.Background()
+	key := usageKey("day", time.Now().UTC().Format(usageDateLayout), AnonymousCaller)
+	spent := int64(2 * microsPerUSD)
+	if err := RedisClient.HIncrBy(ctx, key, "cost_micros", spent).Err(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { RedisClient.HIncrBy(ctx, key, "cost_micros", -spent) })
+
+	for _, caller := range []string{AnonymousCaller, "key_0123456789ab", "key_ba9876543210"} {
+		err := budgets.Check(WithUsageScope(ctx, caller, ""))
+		var exceeded *BudgetExceededError
+		if !errors.As(err, &exceeded) || exceeded.Scope != AnonymousCaller || exceeded.Period != BudgetPeriodDaily {
+			t.Errorf("Check(%q) = %v, esperado teto diário de %s atingido", caller, err, AnonymousCaller)
+		}
+	}
+	if err := budgets.Check(WithUsageScope(ctx, "erp", "")); err != nil {
+		t.Errorf("Check(erp) = %v, esperado nil", err)
+	}
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"github.com/gofiber/fiber/v2/log"
+	"gosmart/config"
+	"strings"
+)
+
+// AnonymousCaller identifica requisições sem X-API-Key.
+const AnonymousCaller = "anonymous"
+
+// apiKeyNames associa chaves de API a nomes legíveis, configurados em API_KEYS.
+var apiKeyNames = map[string]string{}
+
+// adminAPIKey libera as rotas /admin; vazia, as rotas ficam desativadas.
+var adminAPIKey string
+
+// InitCallers lê API_KEYS no formato "nome=chave,nome2=chave2" e a chave de administração ADMIN_API_KEY.
+func InitCallers() {
+	adminAPIKey = config.GetEnv("ADMIN_API_KEY")
+	for _, entry := range splitList(config.GetEnv("API_KEYS")) {
+		name, key, ok := strings.Cut(entry, "=")
+		name, key = strings.TrimSpace(name), strings.TrimSpace(key)
+		if !ok || name == "" || key == "" {
+			log.Warnf("entrada inválida em API_KEYS: %s", entry)
+			continue
+		}
+		apiKeyNames[key] = name
+	}
+}
+
+// CallerID identifica quem fez a requisição a partir da chave de API: o nome configurado em API_KEYS ou,
+// para requisições sem chave ou com chave desconhecida, AnonymousCaller. Chaves desconhecidas caem todas no
+// mesmo chamador, para que trocar de chave não abra uma nova cota de limites e tetos de gasto.
+func CallerID(apiKey string) string {
+	if name, ok := apiKeyNames[apiKey]; ok && apiKey != "" {
+		return name
+	}
+	return AnonymousCaller
+}
+
+// APIKeysEnabled indica se API_KEYS foi configurada; nesse caso, chaves desconhecidas são recusadas.
+func APIKeysEnabled() bool {
+	return len(apiKeyNames) > 0
+}
+
+// KnownAPIKey indica se a chave está configurada em API_KEYS.
+func KnownAPIKey(apiKey string) bool {
+	_, ok := apiKeyNames[apiKey]
+	return ok
+}
+
+// KnownCaller indica se caller é AnonymousCaller ou um dos nomes configurados em API_KEYS.
+func KnownCaller(caller string) bool {
+	if caller == AnonymousCaller {
+		return true
+	}
+	for _, name := range apiKeyNames {
+		if name == caller {
+			return true
+		}
+	}
+	return false
+}
+
+// AdminEnabled indica se ADMIN_API_KEY foi configurada.
+func AdminEnabled() bool {
+	return adminAPIKey != ""
+}
+
+// IsAdmin confere a chave de administração em tempo constante.
+func IsAdmin(key string) bool {
+	return adminAPIKey != "" && subtle.ConstantTimeCompare([]byte(key), []byte(adminAPIKey)) == 1
+}
+
+type usageScopeKey struct{}
+
+// usageScope identifica a quem atribuir o consumo das chamadas ao LLM feitas com o contexto.
+type usageScope struct {
+	Caller     string
+	DocumentID string
+}
+
+// WithUsageScope associa o chamador e o documento ao contexto, para a contabilização de consumo.
+func WithUsageScope(ctx context.Context, caller string, documentID string) context.Context {
+	return context.WithValue(ctx, usageScopeKey{}, usageScope{Caller: caller, DocumentID: documentID})
+}
+
+func scopeFromContext(ctx context.Context) usageScope {
+	scope, _ := ctx.Value(usageScopeKey{}).(usageScope)
+	// Chamadores fora de API_KEYS, como os de jobs gravados antes de uma mudança nas chaves, vão para o
+	// chamador compartilhado, para não ganharem consumo e tetos de gasto próprios
+	if !KnownCaller(scope.Caller) {
+		scope.Caller = AnonymousCaller
+	}
+	return scope
+}
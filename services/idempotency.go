@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"time"
+)
+
+const (
+	idempotencyKeyPrefix       = "gosmart:idempotency:"
+	idempotencyStateInFlight   = "in_flight"
+	idempotencyStateCompleted  = "completed"
+	defaultIdempotencyTTL      = 24 * time.Hour
+	defaultIdempotencyLockTTL  = 30 * time.Minute
+	maxIdempotencyAcquireTries = 3
+)
+
+var (
+	ErrIdempotencyInFlight = errors.New("requisição com a mesma Idempotency-Key ainda em andamento")
+	ErrIdempotencyMismatch = errors.New("Idempotency-Key já usada com outra requisição (query string ou corpo diferente)")
+)
+
+// IdempotencyRecord é o estado de uma Idempotency-Key: em andamento ou com a resposta final gravada.
+type IdempotencyRecord struct {
+	State       string    `json:"state"`
+	BodyHash    string    `json:"body_hash"`
+	Status      int       `json:"status,omitempty"`
+	ContentType string    `json:"content_type,omitempty"`
+	Location    string    `json:"location,omitempty"`
+	Body        []byte    `json:"body,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// IdempotencyStore guarda no Redis as respostas de requisições com Idempotency-Key. A marca de
+// andamento expira após lockTTL, para que uma instância encerrada no meio do processamento não
+// bloqueie a chave; respostas finais ficam disponíveis por ttl.
+type IdempotencyStore struct {
+	ttl     time.Duration
+	lockTTL time.Duration
+}
+
+var Idempotency *IdempotencyStore
+
+// InitIdempotency lê IDEMPOTENCY_TTL e IDEMPOTENCY_LOCK_TTL.
+func InitIdempotency() {
+	Idempotency = &IdempotencyStore{
+		ttl:     durationFromEnv("IDEMPOTENCY_TTL", defaultIdempotencyTTL),
+		lockTTL: durationFromEnv("IDEMPOTENCY_LOCK_TTL", defaultIdempotencyLockTTL),
+	}
+}
+
+// Begin reserva a chave para esta requisição. Devolve nil quando a reserva foi feita e a requisição deve
+// ser processada, ou o registro concluído quando a resposta gravada deve ser repetida. Chaves em andamento
+// ou usadas com outra query string ou outro corpo devolvem ErrIdempotencyInFlight e ErrIdempotencyMismatch.
+func (s *IdempotencyStore) Begin(ctx context.Context, key string, bodyHash string) (*IdempotencyRecord, error) {
+	if !RedisAvailable() {
+		return nil, errRedisUnavailable
+	}
+
+	marker, err := json.Marshal(IdempotencyRecord{State: idempotencyStateInFlight, BodyHash: bodyHash, CreatedAt: time.Now()})
+	if err != nil {
+		return nil, err
+	}
+
+	for try := 0; try < maxIdempotencyAcquireTries; try++ {
+		acquired, err := RedisClient.SetNX(ctx, idempotencyKeyPrefix+key, marker, s.lockTTL).Result()
+		if err != nil {
+			return nil, fmt.Errorf("erro ao reservar Idempotency-Key: %w", err)
+		}
+		if acquired {
+			return nil, nil
+		}
+
+		data, err := RedisClient.Get(ctx, idempotencyKeyPrefix+key).Bytes()
+		if errors.Is(err, redis.Nil) {
+			// A chave expirou ou foi liberada entre o SETNX e o GET
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("erro ao ler Idempotency-Key: %w", err)
+		}
+
+		var record IdempotencyRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			return nil, fmt.Errorf("erro ao deserializar Idempotency-Key: %w", err)
+		}
+		if record.BodyHash != bodyHash {
+			return nil, ErrIdempotencyMismatch
+		}
+		if record.State != idempotencyStateCompleted {
+			return nil, ErrIdempotencyInFlight
+		}
+		return &record, nil
+	}
+	return nil, ErrIdempotencyInFlight
+}
+
+// Complete grava a resposta final da chave reservada.
+func (s *IdempotencyStore) Complete(ctx context.Context, key string, record IdempotencyRecord) error {
+	record.State = idempotencyStateCompleted
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if err := RedisClient.Set(ctx, idempotencyKeyPrefix+key, data, s.ttl).Err(); err != nil {
+		return fmt.Errorf("erro ao gravar resposta da Idempotency-Key: %w", err)
+	}
+	return nil
+}
+
+// Release libera a chave sem gravar resposta, permitindo que a requisição seja repetida.
+func (s *IdempotencyStore) Release(ctx context.Context, key string) {
+	RedisClient.Del(ctx, idempotencyKeyPrefix+key)
+}
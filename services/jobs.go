@@ -0,0 +1,569 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"github.com/gofiber/fiber/v2/log"
+	"gosmart/config"
+	"gosmart/entities"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	jobKeyPrefix      = "gosmart:job:"
+	jobPagesKeySuffix = ":pages"
+	activeJobsKey     = "gosmart:jobs:active"
+	defaultJobTTL     = 24 * time.Hour
+	jobPollInterval   = 500 * time.Millisecond
+	defaultJobWait    = 30 * time.Minute
+	jobUpdateAttempts = 10
+)
+
+var (
+	ErrJobNotFound    = errors.New("job não encontrado")
+	ErrJobWaitTimeout = errors.New("tempo de espera pelo job esgotado")
+)
+
+// storedJob é o formato persistido no Redis: o estado público do job e o necessário para retomá-lo.
+// Os resultados das páginas ficam em um hash separado para que os workers gravem em paralelo.
+type storedJob struct {
+	entities.Job
+	PDFPath string            `json:"pdf_path"`
+	WorkDir string            `json:"work_dir"`
+	Options ProcessingOptions `json:"options"`
+}
+
+// JobManager coordena os jobs de processamento de PDF: prepara o documento, publica as páginas
+// na PageQueue e consolida os resultados gravados pelos workers. Todo o estado fica no Redis,
+// permitindo consultar o progresso, cancelar e retomar jobs interrompidos por uma reinicialização.
+type JobManager struct {
+	ttl     time.Duration
+	maxWait time.Duration
+
+	mu      sync.Mutex
+	cancels map[string]map[string]context.CancelFunc
+}
+
+var Jobs *JobManager
+
+func NewJobManager(ttl time.Duration, maxWait time.Duration) *JobManager {
+	return &JobManager{
+		ttl:     ttl,
+		maxWait: maxWait,
+		cancels: make(map[string]map[string]context.CancelFunc),
+	}
+}
+
+// InitJobs inicializa o gerenciador de jobs. Deve ser chamado depois de InitRedis.
+func InitJobs() {
+	ttl := defaultJobTTL
+	if value := config.GetEnv("JOBS_TTL"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			log.Warnf("JOBS_TTL inválido (%s), usando %s", value, defaultJobTTL)
+		} else {
+			ttl = parsed
+		}
+	}
+
+	Jobs = NewJobManager(ttl, durationFromEnv("JOBS_WAIT_TIMEOUT", defaultJobWait))
+}
+
+// Submit registra um novo job para o PDF já salvo em pdfPath e inicia a preparação do documento.
+// caller identifica quem enviou o PDF, para a contabilização de consumo (ver CallerID).
+func (m *JobManager) Submit(ctx context.Context, id string, caller string, pdfPath string, workDir string, options ProcessingOptions) (*entities.Job, error) {
+	fileHash, err := FileHash(pdfPath)
+	if err != nil {
+		log.Warnf("erro ao calcular hash do PDF do job %s: %v", id, err)
+	}
+
+	now := time.Now()
+	stored := &storedJob{
+		Job: entities.Job{
+			ID:        id,
+			Caller:    caller,
+			FileHash:  fileHash,
+			Status:    entities.JobStatusQueued,
+			Mode:      options.Mode,
+			OCREngine: options.OCREngine,
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		PDFPath: pdfPath,
+		WorkDir: workDir,
+		Options: options,
+	}
+
+	if err := m.save(ctx, stored); err != nil {
+		return nil, err
+	}
+	if err := RedisClient.SAdd(ctx, activeJobsKey, id).Err(); err != nil {
+		return nil, fmt.Errorf("erro ao registrar job ativo: %w", err)
+	}
+
+	job := stored.Job
+	go m.prepare(stored)
+
+	return &job, nil
+}
+
+// Get devolve o estado atual do job com os resultados parciais.
+func (m *JobManager) Get(ctx context.Context, id string) (*entities.Job, error) {
+	stored, err := m.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.loadResults(ctx, stored); err != nil {
+		return nil, err
+	}
+	return &stored.Job, nil
+}
+
+// Wait aguarda o job chegar a um estado final e devolve o estado completo.
+// Depois de JOBS_WAIT_TIMEOUT devolve ErrJobWaitTimeout; o job continua e pode ser consultado em /jobs/:id.
+func (m *JobManager) Wait(ctx context.Context, id string) (*entities.Job, error) {
+	if m.maxWait > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.maxWait)
+		defer cancel()
+	}
+
+	ticker := time.NewTicker(jobPollInterval)
+	defer ticker.Stop()
+
+	for {
+		stored, err := m.load(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if stored.Finished() {
+			return m.Get(ctx, id)
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, ErrJobWaitTimeout
+			}
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Cancel marca o job como cancelado e interrompe as páginas dele que estejam rodando nesta instância.
+// Páginas ainda na fila são descartadas pelos workers. Jobs já finalizados são devolvidos sem alteração.
+func (m *JobManager) Cancel(ctx context.Context, id string) (*entities.Job, error) {
+	stored, err := m.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !stored.Finished() {
+		m.finish(ctx, id, entities.JobStatusCanceled, "")
+	}
+
+	m.mu.Lock()
+	for _, cancel := range m.cancels[id] {
+		cancel()
+	}
+	m.mu.Unlock()
+
+	return m.Get(ctx, id)
+}
+
+// Resume retoma os jobs que ainda não tinham publicado suas páginas quando o processo foi encerrado.
+// Jobs em execução não precisam ser retomados: prepare só os marca como running depois de publicar as páginas,
+// que continuam na fila.
+func (m *JobManager) Resume(ctx context.Context) {
+	ids, err := RedisClient.SMembers(ctx, activeJobsKey).Result()
+	if err != nil {
+		log.Error("erro ao listar jobs ativos: ", err)
+		return
+	}
+
+	for _, id := range ids {
+		stored, err := m.load(ctx, id)
+		if err != nil {
+			log.Warnf("job %s ativo não pôde ser carregado: %v", id, err)
+			RedisClient.SRem(ctx, activeJobsKey, id)
+			continue
+		}
+		if stored.Finished() {
+			RedisClient.SRem(ctx, activeJobsKey, id)
+			continue
+		}
+		if stored.Status != entities.JobStatusQueued {
+			continue
+		}
+		if _, err := os.Stat(stored.PDFPath); err != nil {
+			m.finish(ctx, id, entities.JobStatusFailed, "arquivo PDF não está mais disponível para retomar o job")
+			continue
+		}
+
+		log.Infof("retomando job %s", id)
+		go m.prepare(stored)
+	}
+}
+
+// prepare lê e rasteriza o PDF e publica as páginas na fila.
+func (m *JobManager) prepare(stored *storedJob) {
+	ctx := context.Background()
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("pânico ao preparar PDF do job %s: %v", stored.ID, r)
+			m.finish(ctx, stored.ID, entities.JobStatusFailed, "Erro interno ao preparar PDF")
+		}
+	}()
+
+	pages, err := PreparePDF(ctx, stored.PDFPath, stored.WorkDir)
+	if err != nil {
+		log.Errorf("erro ao preparar PDF do job %s: %v", stored.ID, err)
+		m.finish(ctx, stored.ID, entities.JobStatusFailed, "Erro ao converter PDF para imagens")
+		return
+	}
+
+	if len(pages) == 0 {
+		m.finish(ctx, stored.ID, entities.JobStatusCompleted, "")
+		return
+	}
+
+	// O total de páginas precisa estar salvo antes de os workers começarem a gravar resultados. O job só
+	// passa a running depois de as páginas estarem na fila: se o processo cair antes disso, ele continua
+	// queued e Resume o prepara de novo (páginas publicadas duas vezes são gravadas uma única vez).
+	// Um job cancelado durante a preparação não é sobrescrito e não publica as páginas.
+	updated, err := m.update(ctx, stored.ID, func(current *storedJob) bool {
+		if current.Finished() {
+			return false
+		}
+		current.TotalPages = len(pages)
+		return true
+	})
+	if err != nil {
+		log.Errorf("erro ao salvar job %s: %v", stored.ID, err)
+		return
+	}
+	if updated == nil {
+		return
+	}
+	if err := Pages.Enqueue(ctx, stored.ID, stored.Options, pages); err != nil {
+		log.Errorf("erro ao enfileirar páginas do job %s: %v", stored.ID, err)
+		m.finish(ctx, stored.ID, entities.JobStatusFailed, "Erro ao enfileirar páginas")
+		return
+	}
+	if err := m.markRunning(ctx, stored.ID); err != nil {
+		log.Errorf("erro ao marcar job %s em execução: %v", stored.ID, err)
+	}
+}
+
+// markRunning passa o job de queued para running, sem sobrescrever um job que os workers já finalizaram
+// ou que foi cancelado enquanto as páginas eram publicadas.
+func (m *JobManager) markRunning(ctx context.Context, id string) error {
+	_, err := m.update(ctx, id, func(stored *storedJob) bool {
+		if stored.Status != entities.JobStatusQueued {
+			return false
+		}
+		stored.Status = entities.JobStatusRunning
+		return true
+	})
+	return err
+}
+
+// update aplica change ao job em transação (WATCH): se outra instância alterar o job entre a leitura e a
+// gravação, a leitura é refeita e change é aplicada de novo sobre o estado atual. change devolve false para
+// deixar o job como está. Devolve o job gravado, ou nil quando change não o alterou.
+func (m *JobManager) update(ctx context.Context, id string, change func(*storedJob) bool) (*storedJob, error) {
+	key := jobKeyPrefix + id
+	for attempt := 0; attempt < jobUpdateAttempts; attempt++ {
+		var updated *storedJob
+		err := RedisClient.Watch(ctx, func(tx *redis.Tx) error {
+			data, err := tx.Get(ctx, key).Bytes()
+			if errors.Is(err, redis.Nil) {
+				return ErrJobNotFound
+			}
+			if err != nil {
+				return fmt.Errorf("erro ao ler job do Redis: %w", err)
+			}
+			var stored storedJob
+			if err := json.Unmarshal(data, &stored); err != nil {
+				return fmt.Errorf("erro ao deserializar job: %w", err)
+			}
+			if !change(&stored) {
+				return nil
+			}
+
+			stored.UpdatedAt = time.Now()
+			data, err = m.marshal(&stored)
+			if err != nil {
+				return err
+			}
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.Set(ctx, key, data, m.ttl)
+				return nil
+			})
+			if err == nil {
+				updated = &stored
+			}
+			return err
+		}, key)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		return updated, err
+	}
+	return nil, fmt.Errorf("job %s alterado por outra operação em todas as %d tentativas de gravação", id, jobUpdateAttempts)
+}
+
+// ProcessTask processa uma página retirada da fila e grava o resultado no job.
+// Devolve nil quando a mensagem pode ser confirmada: o resultado foi gravado ou a página foi descartada
+// porque o job não existe mais, já terminou ou foi cancelado. Qualquer outro erro é transitório e a
+// página deve continuar pendente na fila para ser reprocessada.
+func (m *JobManager) ProcessTask(ctx context.Context, task pageTask) error {
+	stored, err := m.load(ctx, task.JobID)
+	if errors.Is(err, ErrJobNotFound) {
+		log.Warnf("descartando página %d do job %s: %v", task.Page.Number, task.JobID, err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if stored.Finished() {
+		return nil
+	}
+
+	// Uma página reentregue pela fila pode já ter sido gravada antes de a confirmação falhar;
+	// nesse caso basta refazer a verificação de conclusão do job.
+	pagesKey := jobKeyPrefix + task.JobID + jobPagesKeySuffix
+	exists, err := RedisClient.HExists(ctx, pagesKey, strconv.Itoa(task.Page.Number)).Result()
+	if err != nil {
+		return fmt.Errorf("erro ao consultar resultado da página %d do job %s: %w", task.Page.Number, task.JobID, err)
+	}
+	if exists {
+		done, err := RedisClient.HLen(ctx, pagesKey).Result()
+		if err != nil {
+			return fmt.Errorf("erro ao contar páginas do job %s: %w", task.JobID, err)
+		}
+		return m.completeIfDone(ctx, task.JobID, done)
+	}
+
+	taskCtx, cancel := context.WithCancel(WithUsageScope(ctx, stored.Caller, stored.ID))
+	key := strconv.Itoa(task.Page.Number)
+	m.mu.Lock()
+	if m.cancels[task.JobID] == nil {
+		m.cancels[task.JobID] = make(map[string]context.CancelFunc)
+	}
+	m.cancels[task.JobID][key] = cancel
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		delete(m.cancels[task.JobID], key)
+		if len(m.cancels[task.JobID]) == 0 {
+			delete(m.cancels, task.JobID)
+		}
+		m.mu.Unlock()
+		cancel()
+	}()
+
+	result := ProcessPage(taskCtx, task.JobID, task.Page, task.Options)
+	if taskCtx.Err() != nil {
+		// Só o Cancel do job interrompe taskCtx: a página é descartada.
+		return nil
+	}
+
+	return m.StorePageResult(ctx, result)
+}
+
+// StorePageResult grava o resultado de uma página e finaliza o job quando todas as páginas terminaram.
+// Gravar a mesma página mais de uma vez é seguro, o que permite o processamento at-least-once da fila.
+func (m *JobManager) StorePageResult(ctx context.Context, result entities.PageResult) error {
+	id, page := result.DocumentID, result.Page
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("erro ao serializar resultado da página %d do job %s: %w", page, id, err)
+	}
+
+	pagesKey := jobKeyPrefix + id + jobPagesKeySuffix
+	var done *redis.IntCmd
+	_, err = RedisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, pagesKey, strconv.Itoa(page), data)
+		pipe.Expire(ctx, pagesKey, m.ttl)
+		done = pipe.HLen(ctx, pagesKey)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("erro ao gravar resultado da página %d do job %s: %w", page, id, err)
+	}
+
+	return m.completeIfDone(ctx, id, done.Val())
+}
+
+// completeIfDone finaliza o job quando done páginas cobrem o total. Jobs removidos ou já finalizados são ignorados.
+func (m *JobManager) completeIfDone(ctx context.Context, id string, done int64) error {
+	stored, err := m.update(ctx, id, func(stored *storedJob) bool {
+		if stored.Finished() || done < int64(stored.TotalPages) {
+			return false
+		}
+		stored.Status = entities.JobStatusCompleted
+		stored.Error = ""
+		return true
+	})
+	if errors.Is(err, ErrJobNotFound) {
+		return nil
+	}
+	if err != nil {
+		log.Errorf("erro ao salvar job %s: %v", id, err)
+		return err
+	}
+	if stored != nil {
+		m.release(ctx, stored)
+	}
+	return nil
+}
+
+// finish grava o estado final do job e libera seu diretório de trabalho. Só a primeira de transições
+// concorrentes (cancelamento, conclusão, falha) é gravada e auditada; as que encontram o job já finalizado
+// não o alteram. Se o estado não puder ser gravado, o diretório é mantido e o erro é devolvido.
+func (m *JobManager) finish(ctx context.Context, id string, status entities.JobStatus, message string) error {
+	stored, err := m.update(ctx, id, func(stored *storedJob) bool {
+		if stored.Finished() {
+			return false
+		}
+		stored.Status = status
+		stored.Error = message
+		return true
+	})
+	if err != nil {
+		log.Errorf("erro ao salvar job %s: %v", id, err)
+		return err
+	}
+	if stored != nil {
+		m.release(ctx, stored)
+	}
+	return nil
+}
+
+// release tira o job finalizado da lista de ativos, libera seu diretório de trabalho e grava o evento de auditoria.
+func (m *JobManager) release(ctx context.Context, stored *storedJob) {
+	RedisClient.SRem(ctx, activeJobsKey, stored.ID)
+	WorkDirs.Release(stored.WorkDir)
+	m.audit(ctx, stored)
+}
+
+// audit grava o evento de fim do job, com o consumo acumulado pelas páginas processadas nos workers.
+func (m *JobManager) audit(ctx context.Context, stored *storedJob) {
+	if Audit == nil {
+		return
+	}
+
+	event := entities.AuditEvent{
+		Time:         time.Now(),
+		Kind:         entities.AuditKindJob,
+		Caller:       stored.Caller,
+		DocumentID:   stored.ID,
+		DocumentHash: stored.FileHash,
+		Status:       string(stored.Status),
+		DurationMs:   time.Since(stored.CreatedAt).Milliseconds(),
+		Error:        stored.Error,
+	}
+	if Usage != nil {
+		if usage, err := Usage.Document(ctx, stored.ID); err == nil {
+			event.PromptTokens = usage.PromptTokens
+			event.CompletionTokens = usage.CompletionTokens
+			event.TotalTokens = usage.TotalTokens
+		}
+	}
+	Audit.Record(ctx, event)
+}
+
+// loadResults preenche os resultados e contadores de progresso a partir do hash de páginas.
+func (m *JobManager) loadResults(ctx context.Context, stored *storedJob) error {
+	pages, err := RedisClient.HGetAll(ctx, jobKeyPrefix+stored.ID+jobPagesKeySuffix).Result()
+	if err != nil {
+		return fmt.Errorf("erro ao ler resultados do job: %w", err)
+	}
+
+	stored.Results = make([]*entities.PageResult, stored.TotalPages)
+	stored.CompletedPages = 0
+	stored.FailedPages = 0
+	for field, data := range pages {
+		page, err := strconv.Atoi(field)
+		if err != nil || page < 1 || page > stored.TotalPages {
+			continue
+		}
+
+		var result entities.PageResult
+		if err := json.Unmarshal([]byte(data), &result); err != nil {
+			return fmt.Errorf("erro ao deserializar resultado da página %d: %w", page, err)
+		}
+
+		stored.Results[page-1] = &result
+		stored.CompletedPages++
+		if result.Failed() {
+			stored.FailedPages++
+		}
+	}
+
+	if stored.Status == entities.JobStatusCompleted {
+		stored.Products = MergeProducts(stored.Results)
+	}
+
+	if Usage != nil {
+		usage, err := Usage.Document(ctx, stored.ID)
+		if err != nil {
+			log.Warnf("consumo do job %s indisponível: %v", stored.ID, err)
+		} else if usage.Calls > 0 {
+			stored.Usage = &usage.UsageSummary
+		}
+	}
+
+	return nil
+}
+
+func (m *JobManager) load(ctx context.Context, id string) (*storedJob, error) {
+	data, err := RedisClient.Get(ctx, jobKeyPrefix+id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler job do Redis: %w", err)
+	}
+
+	var stored storedJob
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("erro ao deserializar job: %w", err)
+	}
+	return &stored, nil
+}
+
+func (m *JobManager) save(ctx context.Context, stored *storedJob) error {
+	data, err := m.marshal(stored)
+	if err != nil {
+		return err
+	}
+	if err := RedisClient.Set(ctx, jobKeyPrefix+stored.ID, data, m.ttl).Err(); err != nil {
+		return fmt.Errorf("erro ao salvar job no Redis: %w", err)
+	}
+	return nil
+}
+
+// marshal serializa o job para o Redis; os resultados são mantidos apenas no hash de páginas.
+func (m *JobManager) marshal(stored *storedJob) ([]byte, error) {
+	persisted := *stored
+	persisted.Results = nil
+	persisted.Products = nil
+	persisted.Usage = nil
+	persisted.CompletedPages = 0
+	persisted.FailedPages = 0
+
+	data, err := json.Marshal(persisted)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao serializar job: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"gosmart/entities"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// saveTestJob grava um job em execução com uma página e o remove no fim do teste.
+func saveTestJob(t *testing.T, m *JobManager) string {
+	t.Helper()
+	ctx := context.Background()
+	stored := &storedJob{
+		Job:     entities.Job{ID: "teste-" + uuid.New().String(), Status: entities.JobStatusRunning, TotalPages: 1, CreatedAt: time.Now()},
+		WorkDir: t.TempDir(),
+	}
+	if err := m.save(ctx, stored); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { RedisClient.Del(ctx, jobKeyPrefix+stored.ID) })
+	return stored.ID
+}
+
+func useTestJobs(t *testing.T) *JobManager {
+	t.Helper()
+	useTestRedis(t)
+	if WorkDirs == nil {
+		WorkDirs = &WorkDirManager{retention: time.Hour}
+	}
+	return NewJobManager(time.Minute, time.Minute)
+}
+
+func TestJobFinishKeepsFirstFinalState(t *testing.T) {
+	m := useTestJobs(t)
+	ctx := context.Background()
+	id := saveTestJob(t, m)
+
+	if err := m.finish(ctx, id, entities.JobStatusCanceled, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.completeIfDone(ctx, id, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.finish(ctx, id, entities.JobStatusFailed, "falha"); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := m.load(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != entities.JobStatusCanceled || stored.Error != "" {
+		t.Errorf("status = %s (%q), esperado %s", stored.Status, stored.Error, entities.JobStatusCanceled)
+	}
+}
+
+func TestJobUpdateAppliesOneConcurrentTransition(t *testing.T) {
+	m := useTestJobs(t)
+	ctx := context.Background()
+	id := saveTestJob(t, m)
+
+	statuses := []entities.JobStatus{entities.JobStatusCanceled, entities.JobStatusCompleted, entities.JobStatusFailed}
+	var applied atomic.Int32
+	var winner atomic.Value
+	var wg sync.WaitGroup
+	for i := 0; i < 30; i++ {
+		status := statuses[i%len(statuses)]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			updated, err := m.update(ctx, id, func(stored *storedJob) bool {
+				if stored.Finished() {
+					return false
+				}
+				stored.Status = status
+				return true
+			})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if updated != nil {
+				applied.Add(1)
+				winner.Store(status)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := applied.Load(); got != 1 {
+		t.Fatalf("%d transições gravadas, esperado 1", got)
+	}
+	stored, err := m.load(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != winner.Load() {
+		t.Errorf("status = %s, esperado %s", stored.Status, winner.Load())
+	}
+}
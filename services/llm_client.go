@@ -0,0 +1,235 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v2/log"
+	"gosmart/config"
+	"gosmart/entities"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultOpenAIBaseURL = "https://api.openai.com/v1"
+	defaultOpenAITimeout = 120 * time.Second
+)
+
+// LLMClient concentra o acesso HTTP à API de chat completions da OpenAI.
+// É criado uma única vez na inicialização e compartilhado por todas as requisições.
+type LLMClient struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+	retry      RetryPolicy
+}
+
+var OpenAIClient *LLMClient
+
+// NewLLMClient cria um cliente com um transporte HTTP compartilhado entre as chamadas.
+func NewLLMClient(baseURL string, apiKey string, timeout time.Duration) *LLMClient {
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   10,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+
+	return &LLMClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		apiKey:  apiKey,
+		httpClient: &http.Client{
+			Transport: transport,
+			Timeout:   timeout,
+		},
+		retry: DefaultRetryPolicy,
+	}
+}
+
+// SetRetryPolicy substitui a política de novas tentativas do cliente.
+func (c *LLMClient) SetRetryPolicy(policy RetryPolicy) {
+	c.retry = policy
+}
+
+// InitLLMClient lê a configuração da OpenAI do ambiente e inicializa OpenAIClient.
+// OPENAI_API_URL (endpoint completo de chat completions) continua aceito por compatibilidade.
+func InitLLMClient() {
+	baseURL := config.GetEnv("OPENAI_BASE_URL")
+	if baseURL == "" {
+		baseURL = strings.TrimSuffix(config.GetEnv("OPENAI_API_URL"), "/chat/completions")
+	}
+	if baseURL == "" {
+		baseURL = defaultOpenAIBaseURL
+	}
+
+	timeout := defaultOpenAITimeout
+	if value := config.GetEnv("OPENAI_TIMEOUT"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			log.Warnf("OPENAI_TIMEOUT inválido (%s), usando %s", value, defaultOpenAITimeout)
+		} else {
+			timeout = parsed
+		}
+	}
+
+	OpenAIClient = NewLLMClient(baseURL, config.GetEnv("OPENAI_API_KEY"), timeout)
+	OpenAIClient.SetRetryPolicy(retryPolicyFromEnv())
+}
+
+func retryPolicyFromEnv() RetryPolicy {
+	policy := DefaultRetryPolicy
+
+	if value := config.GetEnv("OPENAI_MAX_ATTEMPTS"); value != "" {
+		attempts, err := strconv.Atoi(value)
+		if err != nil || attempts < 1 {
+			log.Warnf("OPENAI_MAX_ATTEMPTS inválido (%s), usando %d", value, policy.MaxAttempts)
+		} else {
+			policy.MaxAttempts = attempts
+		}
+	}
+
+	durations := map[string]*time.Duration{
+		"OPENAI_RETRY_BASE_DELAY":  &policy.BaseDelay,
+		"OPENAI_RETRY_MAX_DELAY":   &policy.MaxDelay,
+		"OPENAI_RETRY_MAX_ELAPSED": &policy.MaxElapsed,
+	}
+	for key, target := range durations {
+		value := config.GetEnv(key)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			log.Warnf("%s inválido (%s), usando %s", key, value, *target)
+			continue
+		}
+		*target = parsed
+	}
+
+	return policy
+}
+
+// ChatCompletion envia uma requisição ao endpoint /chat/completions e devolve a resposta decodificada.
+func (c *LLMClient) ChatCompletion(ctx context.Context, request entities.ChatCompletionRequest) (*entities.ChatCompletionResponse, error) {
+	var response entities.ChatCompletionResponse
+	attempts, err := c.doJSON(ctx, http.MethodPost, "/chat/completions", request, &response)
+	if err != nil {
+		return nil, err
+	}
+	response.Attempts = attempts
+
+	if len(response.Choices) == 0 {
+		return nil, &LLMError{Attempts: attempts, Err: errors.New("nenhuma resposta válida retornada")}
+	}
+
+	return &response, nil
+}
+
+// ListModels consulta o endpoint /models.
+func (c *LLMClient) ListModels(ctx context.Context) ([]entities.OpenAIModel, error) {
+	var response struct {
+		Data []entities.OpenAIModel `json:"data"`
+	}
+	if _, err := c.doJSON(ctx, http.MethodGet, "/models", nil, &response); err != nil {
+		return nil, err
+	}
+
+	return response.Data, nil
+}
+
+// doJSON executa a requisição com novas tentativas para falhas transitórias e devolve o número de tentativas feitas.
+func (c *LLMClient) doJSON(ctx context.Context, method string, path string, payload interface{}, out interface{}) (int, error) {
+	if c.apiKey == "" {
+		return 0, errors.New("OPENAI_API_KEY não definido no arquivo .env")
+	}
+
+	var data []byte
+	if payload != nil {
+		var err error
+		data, err = json.Marshal(payload)
+		if err != nil {
+			return 0, fmt.Errorf("erro ao serializar o payload: %w", err)
+		}
+	}
+
+	started := time.Now()
+	attempt := 0
+	for {
+		attempt++
+
+		status, header, respBody, err := c.send(ctx, method, path, data)
+		if err == nil && status == http.StatusOK {
+			if err := json.Unmarshal(respBody, out); err != nil {
+				return attempt, &LLMError{StatusCode: status, Attempts: attempt, Err: fmt.Errorf("erro ao deserializar a resposta: %w", err)}
+			}
+			if attempt > 1 {
+				log.Infof("chamada %s %s concluída após %d tentativas", method, path, attempt)
+			}
+			return attempt, nil
+		}
+
+		llmErr := &LLMError{StatusCode: status, Body: string(respBody), Attempts: attempt, Err: err}
+		retryable := (err != nil && ctx.Err() == nil) || (err == nil && isRetryableStatus(status))
+		if !retryable || attempt >= c.retry.MaxAttempts {
+			return attempt, llmErr
+		}
+
+		delay := c.retry.backoff(attempt, header)
+		if time.Since(started)+delay > c.retry.MaxElapsed {
+			log.Warnf("tempo máximo de novas tentativas excedido para %s %s após %d tentativas", method, path, attempt)
+			return attempt, llmErr
+		}
+
+		log.Warnf("chamada %s %s falhou (tentativa %d/%d), nova tentativa em %s: %v", method, path, attempt, c.retry.MaxAttempts, delay, llmErr)
+		if err := sleepContext(ctx, delay); err != nil {
+			return attempt, &LLMError{Attempts: attempt, Err: err}
+		}
+	}
+}
+
+func (c *LLMClient) send(ctx context.Context, method string, path string, data []byte) (int, http.Header, []byte, error) {
+	var body io.Reader
+	if data != nil {
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("erro ao criar a requisição: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("erro ao enviar a requisição: %w", err)
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			log.Error("erro ao fechar o corpo da resposta: ", err)
+		}
+	}(resp.Body)
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, resp.Header, nil, fmt.Errorf("erro ao ler a resposta: %w", err)
+	}
+
+	return resp.StatusCode, resp.Header, respBody, nil
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy controla as novas tentativas do LLMClient para falhas transitórias (429, 5xx e erros de rede).
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	MaxElapsed  time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   time.Second,
+	MaxDelay:    30 * time.Second,
+	MaxElapsed:  2 * time.Minute,
+}
+
+// LLMError representa uma chamada à OpenAI que falhou, mesmo após as novas tentativas.
+type LLMError struct {
+	StatusCode int
+	Body       string
+	Attempts   int
+	Err        error
+}
+
+func (e *LLMError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%v (tentativas: %d)", e.Err, e.Attempts)
+	}
+	return fmt.Sprintf("requisição falhou com status %d: %s (tentativas: %d)", e.StatusCode, e.Body, e.Attempts)
+}
+
+func (e *LLMError) Unwrap() error {
+	return e.Err
+}
+
+// AttemptsFromError devolve o número de tentativas registrado em um LLMError, ou 0.
+func AttemptsFromError(err error) int {
+	var llmErr *LLMError
+	if errors.As(err, &llmErr) {
+		return llmErr.Attempts
+	}
+	return 0
+}
+
+func isRetryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
+
+// backoff calcula a espera antes da próxima tentativa, priorizando os cabeçalhos enviados pela API.
+// A espera pedida pela API não é limitada por MaxDelay: tentar antes dela só gera outro 429, então
+// a chamada espera o tempo todo ou desiste, se ele não couber em MaxElapsed.
+func (p RetryPolicy) backoff(attempt int, header http.Header) time.Duration {
+	if delay, ok := delayFromHeaders(header); ok {
+		return max(delay, 0)
+	}
+
+	delay := p.BaseDelay << (attempt - 1)
+	if delay <= 0 || delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	// Jitter: espera aleatória entre metade e o total do intervalo calculado.
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+func delayFromHeaders(header http.Header) (time.Duration, bool) {
+	if header == nil {
+		return 0, false
+	}
+
+	if value := header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+			return time.Duration(seconds) * time.Second, true
+		}
+		if date, err := http.ParseTime(value); err == nil {
+			return time.Until(date), true
+		}
+	}
+
+	var longest time.Duration
+	found := false
+	for _, name := range []string{"x-ratelimit-reset-requests", "x-ratelimit-reset-tokens", "x-ratelimit-reset"} {
+		value := header.Get(name)
+		if value == "" {
+			continue
+		}
+		delay, err := time.ParseDuration(value)
+		if err != nil {
+			seconds, convErr := strconv.ParseFloat(value, 64)
+			if convErr != nil {
+				continue
+			}
+			delay = time.Duration(seconds * float64(time.Second))
+		}
+		if delay > longest {
+			longest = delay
+		}
+		found = true
+	}
+
+	return longest, found
+}
+
+func sleepContext(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
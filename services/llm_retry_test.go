@@ -0,0 +1,36 @@
+package services
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBackoffHonoursServerDelay(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 5, BaseDelay: time.Second, MaxDelay: 30 * time.Second, MaxElapsed: 2 * time.Minute}
+
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+	}{
+		{name: "Retry-After abaixo de MaxDelay", header: http.Header{"Retry-After": {"5"}}, want: 5 * time.Second},
+		{name: "Retry-After acima de MaxDelay", header: http.Header{"Retry-After": {"90"}}, want: 90 * time.Second},
+		{name: "x-ratelimit-reset acima de MaxDelay", header: http.Header{"X-Ratelimit-Reset-Tokens": {"1m30s"}}, want: 90 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.backoff(1, tt.header); got != tt.want {
+				t.Errorf("backoff = %s, esperado %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoffWithoutHeadersIsCapped(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: time.Second, MaxDelay: 30 * time.Second}
+	if got := policy.backoff(10, nil); got > policy.MaxDelay || got < policy.MaxDelay/2 {
+		t.Errorf("backoff = %s, esperado entre %s e %s", got, policy.MaxDelay/2, policy.MaxDelay)
+	}
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v2/log"
+	"gosmart/config"
+	"gosmart/entities"
+	"sync"
+	"time"
+)
+
+const (
+	defaultModelCacheTTL = time.Hour
+	modelCacheRedisKey   = "gosmart:openai:models"
+)
+
+// ModelCache mantém em memória (e opcionalmente no Redis) a lista de modelos retornada por /v1/models.
+// Quando a consulta falha, a última lista conhecida continua sendo usada.
+type ModelCache struct {
+	client   *LLMClient
+	ttl      time.Duration
+	useRedis bool
+
+	mu        sync.RWMutex
+	models    []entities.OpenAIModel
+	fetchedAt time.Time
+}
+
+type cachedModels struct {
+	FetchedAt time.Time              `json:"fetched_at"`
+	Models    []entities.OpenAIModel `json:"models"`
+}
+
+var AvailableModels *ModelCache
+
+func NewModelCache(client *LLMClient, ttl time.Duration, useRedis bool) *ModelCache {
+	return &ModelCache{
+		client:   client,
+		ttl:      ttl,
+		useRedis: useRedis,
+	}
+}
+
+// InitModelCache configura o cache de modelos a partir do ambiente e inicia a atualização em segundo plano.
+// Deve ser chamado depois de InitLLMClient e InitRedis.
+func InitModelCache() {
+	ttl := defaultModelCacheTTL
+	if value := config.GetEnv("OPENAI_MODELS_CACHE_TTL"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			log.Warnf("OPENAI_MODELS_CACHE_TTL inválido (%s), usando %s", value, defaultModelCacheTTL)
+		} else {
+			ttl = parsed
+		}
+	}
+
+	useRedis := config.GetEnv("OPENAI_MODELS_CACHE_REDIS") == "true"
+
+	AvailableModels = NewModelCache(OpenAIClient, ttl, useRedis)
+	go AvailableModels.StartRefresh(context.Background())
+}
+
+// Get devolve a lista de modelos, consultando a API apenas quando o cache expirou.
+func (m *ModelCache) Get(ctx context.Context) ([]entities.OpenAIModel, error) {
+	if models, ok := m.fresh(); ok {
+		return models, nil
+	}
+
+	if m.useRedis {
+		if cached, err := m.loadFromRedis(ctx); err == nil {
+			m.store(cached.Models, cached.FetchedAt)
+			if time.Since(cached.FetchedAt) < m.ttl {
+				return cached.Models, nil
+			}
+		}
+	}
+
+	return m.Refresh(ctx)
+}
+
+// Refresh consulta /v1/models e atualiza o cache. Em caso de falha, devolve a última lista conhecida.
+func (m *ModelCache) Refresh(ctx context.Context) ([]entities.OpenAIModel, error) {
+	models, err := m.client.ListModels(ctx)
+	if err != nil {
+		m.mu.RLock()
+		lastKnown := m.models
+		m.mu.RUnlock()
+
+		if len(lastKnown) > 0 {
+			log.Warn("erro ao atualizar lista de modelos, usando a última lista conhecida: ", err)
+			return lastKnown, nil
+		}
+		return nil, err
+	}
+
+	fetchedAt := time.Now()
+	m.store(models, fetchedAt)
+
+	if m.useRedis {
+		if err := m.saveToRedis(ctx, cachedModels{FetchedAt: fetchedAt, Models: models}); err != nil {
+			log.Warn("erro ao salvar lista de modelos no Redis: ", err)
+		}
+	}
+
+	return models, nil
+}
+
+// StartRefresh atualiza a lista periodicamente até o contexto ser cancelado.
+func (m *ModelCache) StartRefresh(ctx context.Context) {
+	ticker := time.NewTicker(m.ttl)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := m.Refresh(ctx); err != nil {
+				log.Warn("erro ao atualizar lista de modelos em segundo plano: ", err)
+			}
+		}
+	}
+}
+
+func (m *ModelCache) fresh() ([]entities.OpenAIModel, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if len(m.models) == 0 || time.Since(m.fetchedAt) >= m.ttl {
+		return nil, false
+	}
+	return m.models, true
+}
+
+func (m *ModelCache) store(models []entities.OpenAIModel, fetchedAt time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if fetchedAt.Before(m.fetchedAt) {
+		return
+	}
+	m.models = models
+	m.fetchedAt = fetchedAt
+}
+
+func (m *ModelCache) loadFromRedis(ctx context.Context) (*cachedModels, error) {
+	if RedisClient == nil {
+		return nil, errors.New("redis não inicializado")
+	}
+
+	data, err := RedisClient.Get(ctx, modelCacheRedisKey).Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	var cached cachedModels
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, fmt.Errorf("erro ao deserializar modelos do Redis: %w", err)
+	}
+	if len(cached.Models) == 0 {
+		return nil, errors.New("lista de modelos vazia no Redis")
+	}
+
+	return &cached, nil
+}
+
+func (m *ModelCache) saveToRedis(ctx context.Context, cached cachedModels) error {
+	if RedisClient == nil {
+		return errors.New("redis não inicializado")
+	}
+
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return fmt.Errorf("erro ao serializar modelos: %w", err)
+	}
+
+	// Sem expiração: a chave também serve de fallback entre reinicializações.
+	return RedisClient.Set(ctx, modelCacheRedisKey, data, 0).Err()
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gosmart/config"
+	"strings"
+)
+
+// Operation identifica o tipo de chamada ao LLM para fins de escolha de modelo.
+type Operation string
+
+const (
+	OperationOCRCleanup Operation = "ocr_cleanup"
+	OperationGeneration Operation = "generation"
+	OperationVision     Operation = "vision"
+)
+
+var ErrNoAllowedModel = errors.New("nenhum modelo permitido está disponível")
+
+// ModelPolicy define como o modelo de uma operação é escolhido.
+// Pinned, quando preenchido, tem precedência sobre Allowed, que é percorrido em ordem de prioridade.
+type ModelPolicy struct {
+	Pinned  string
+	Allowed []string
+}
+
+var defaultModelPolicies = map[Operation]ModelPolicy{
+	OperationOCRCleanup: {Allowed: []string{"gpt-4", "gpt-4-turbo", "gpt-3.5-turbo"}},
+	OperationGeneration: {Allowed: []string{"gpt-4", "gpt-4-turbo", "gpt-3.5-turbo"}},
+	OperationVision:     {Allowed: []string{"gpt-4o", "gpt-4o-mini", "gpt-4-turbo"}},
+}
+
+var ModelPolicies map[Operation]ModelPolicy
+
+// InitModelPolicies carrega as políticas de modelo do ambiente.
+// Para cada operação, OPENAI_MODEL_<OPERAÇÃO> fixa um modelo e OPENAI_MODELS_<OPERAÇÃO>
+// define a lista de modelos permitidos (separados por vírgula, em ordem de prioridade).
+func InitModelPolicies() {
+	ModelPolicies = make(map[Operation]ModelPolicy, len(defaultModelPolicies))
+
+	for operation, policy := range defaultModelPolicies {
+		suffix := strings.ToUpper(string(operation))
+
+		if pinned := strings.TrimSpace(config.GetEnv("OPENAI_MODEL_" + suffix)); pinned != "" {
+			policy.Pinned = pinned
+		}
+		if allowed := splitList(config.GetEnv("OPENAI_MODELS_" + suffix)); len(allowed) > 0 {
+			policy.Allowed = allowed
+		}
+
+		ModelPolicies[operation] = policy
+	}
+}
+
+// SelectModel escolhe o modelo para a operação de acordo com a política configurada.
+// Retorna ErrNoAllowedModel quando nenhum modelo permitido consta na lista da conta.
+func SelectModel(ctx context.Context, operation Operation) (string, error) {
+	policy, ok := ModelPolicies[operation]
+	if !ok {
+		return "", fmt.Errorf("operação sem política de modelo: %s", operation)
+	}
+
+	models, err := AvailableModels.Get(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	available := make(map[string]bool, len(models))
+	for _, model := range models {
+		available[model.ID] = true
+	}
+
+	if policy.Pinned != "" {
+		if !available[policy.Pinned] {
+			return "", fmt.Errorf("%w: modelo fixado %s indisponível para a operação %s", ErrNoAllowedModel, policy.Pinned, operation)
+		}
+		return policy.Pinned, nil
+	}
+
+	for _, model := range policy.Allowed {
+		if available[model] {
+			return model, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: operação %s, permitidos %s", ErrNoAllowedModel, operation, strings.Join(policy.Allowed, ", "))
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"github.com/gofiber/fiber/v2/log"
+	"gosmart/config"
+	"gosmart/entities"
+	"gosmart/ocr"
+	"gosmart/schema"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ProcessingOptions reúne as escolhas feitas na requisição para processar as páginas de um PDF.
+type ProcessingOptions struct {
+	Mode       string      `json:"mode"`
+	OCREngine  string      `json:"ocr_engine"`
+	OCROptions ocr.Options `json:"ocr_options"`
+	// Schema é o esquema de extração; nil deixa o modelo escolher as chaves.
+	Schema *schema.Schema `json:"schema,omitempty"`
+	// IncludeOCRText devolve o texto reconhecido pelo OCR junto com o resultado da página.
+	IncludeOCRText bool `json:"include_ocr_text,omitempty"`
+	// NoCache ignora o cache de resultados na leitura; os resultados novos ainda são gravados.
+	NoCache bool `json:"no_cache,omitempty"`
+}
+
+// DefaultOCREngine e DefaultOCROptions são usados quando a requisição não informa o backend ou as opções.
+var (
+	DefaultOCREngine  = "tesseract"
+	DefaultOCROptions ocr.Options
+)
+
+// InitOCR registra os backends de OCR e carrega os padrões do ambiente.
+func InitOCR() {
+	ocr.Register(ocr.NewTesseract(config.GetEnv("OCR_TESSERACT_PATH")))
+	ocr.Register(ocr.NewVision(TranscribeImage))
+	if config.GetEnv("OCR_FAKE_ENABLED") == "true" {
+		ocr.Register(&ocr.Fake{Default: config.GetEnv("OCR_FAKE_TEXT")})
+	}
+
+	if engine := config.GetEnv("OCR_ENGINE"); engine != "" {
+		if _, err := ocr.Get(engine); err != nil {
+			log.Warnf("OCR_ENGINE inválido (%s), usando %s", engine, DefaultOCREngine)
+		} else {
+			DefaultOCREngine = engine
+		}
+	}
+
+	options := ocr.Options{
+		Languages: splitPlusList(config.GetEnv("OCR_LANGUAGES")),
+		PSM:       intFromEnv("OCR_PSM"),
+		OEM:       optionalIntFromEnv("OCR_OEM"),
+		DPI:       intFromEnv("OCR_DPI"),
+	}
+	if err := options.Validate(); err != nil {
+		log.Warnf("opções de OCR do ambiente ignoradas: %v", err)
+		return
+	}
+	DefaultOCROptions = options
+}
+
+func intFromEnv(key string) int {
+	value := config.GetEnv(key)
+	if value == "" {
+		return 0
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Warnf("%s inválido (%s), ignorando", key, value)
+		return 0
+	}
+	return parsed
+}
+
+// optionalIntFromEnv é como intFromEnv, mas devolve nil para a variável vazia ou inválida, de modo que 0 possa ser configurado.
+func optionalIntFromEnv(key string) *int {
+	value := config.GetEnv(key)
+	if value == "" {
+		return nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Warnf("%s inválido (%s), ignorando", key, value)
+		return nil
+	}
+	return &parsed
+}
+
+// splitPlusList aceita listas separadas por "+" (formato do Tesseract), vírgula ou espaço.
+func splitPlusList(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == '+' || r == ',' || unicode.IsSpace(r)
+	})
+}
+
+// TranscribeImage usa o modelo de visão apenas como OCR: devolve o texto da imagem, sem estruturar em JSON.
+func TranscribeImage(ctx context.Context, mimeType string, image []byte) (string, error) {
+	model, err := SelectModel(ctx, OperationVision)
+	if err != nil {
+		return "", fmt.Errorf("erro ao selecionar o modelo: %w", err)
+	}
+
+	requestBody := entities.ChatCompletionRequest{
+		Model:       model,
+		MaxTokens:   4096,
+		Temperature: temperature(0),
+		Messages: []entities.ChatCompletionMessage{
+			{
+				Role:    "system",
+				Content: "Você é um mecanismo de OCR. Transcreva fielmente o texto da imagem.",
+			},
+			{
+				Role: "user",
+				Parts: []entities.ContentPart{
+					{Type: "text", Text: "Transcreva todo o texto desta página, preservando as linhas e a ordem das colunas das tabelas. Responda somente com o texto transcrito."},
+					imagePart(mimeType, image),
+				},
+			},
+		},
+	}
+
+	content, stats, err := completeText(ctx, requestBody)
+	recordOCRStats(ctx, stats)
+	return content, err
+}
+
+type ocrStatsKey struct{}
+
+// withOCRStats devolve um contexto em que as chamadas ao LLM feitas pelo backend de OCR (vision)
+// somam suas tentativas e seu consumo em stats, já que a interface ocr.Engine devolve apenas o texto.
+func withOCRStats(ctx context.Context, stats *callStats) context.Context {
+	return context.WithValue(ctx, ocrStatsKey{}, stats)
+}
+
+func recordOCRStats(ctx context.Context, stats callStats) {
+	if total, ok := ctx.Value(ocrStatsKey{}).(*callStats); ok {
+		total.Attempts += stats.Attempts
+		total.Usage.Add(stats.Usage)
+	}
+}
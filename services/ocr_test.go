@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"gosmart/entities"
+	"gosmart/ocr"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeOpenAI simula /models e /chat/completions e guarda os corpos das requisições de chat.
+type fakeOpenAI struct {
+	content string
+
+	mu       sync.Mutex
+	requests []map[string]json.RawMessage
+}
+
+func (f *fakeOpenAI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch r.URL.Path {
+	case "/models":
+		_, _ = io.WriteString(w, `{"data":[{"id":"gpt-4"},{"id":"gpt-4o"}]}`)
+	case "/chat/completions":
+		var request map[string]json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.mu.Lock()
+		f.requests = append(f.requests, request)
+		f.mu.Unlock()
+
+		content, _ := json.Marshal(f.content)
+		_, _ = io.WriteString(w, `{"choices":[{"message":{"content":`+string(content)+`},"finish_reason":"stop"}],`+
+			`"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeOpenAI) lastRequest(t *testing.T) map[string]json.RawMessage {
+	t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.requests) == 0 {
+		t.Fatal("nenhuma chamada a /chat/completions")
+	}
+	return f.requests[len(f.requests)-1]
+}
+
+// useFakeOpenAI aponta o cliente, o cache de modelos e as políticas para um servidor de teste.
+func useFakeOpenAI(t *testing.T, content string) *fakeOpenAI {
+	t.Helper()
+	api := &fakeOpenAI{content: content}
+	server := httptest.NewServer(api)
+
+	client, models, policies := OpenAIClient, AvailableModels, ModelPolicies
+	t.Cleanup(func() {
+		server.Close()
+		OpenAIClient, AvailableModels, ModelPolicies = client, models, policies
+	})
+
+	OpenAIClient = NewLLMClient(server.URL, "test", 5*time.Second)
+	AvailableModels = NewModelCache(OpenAIClient, time.Hour, false)
+	ModelPolicies = defaultModelPolicies
+	return api
+}
+
+func TestTranscribeImageSendsZeroTemperature(t *testing.T) {
+	api := useFakeOpenAI(t, "texto transcrito")
+
+	text, err := TranscribeImage(context.Background(), "image/png", []byte("png"))
+	if err != nil {
+		t.Fatalf("TranscribeImage: %v", err)
+	}
+	if text != "texto transcrito" {
+		t.Errorf("texto = %q", text)
+	}
+
+	temperature, ok := api.lastRequest(t)["temperature"]
+	if !ok {
+		t.Fatal("temperature ausente na requisição")
+	}
+	if string(temperature) != "0" {
+		t.Errorf("temperature = %s, esperado 0", temperature)
+	}
+}
+
+func TestProcessPageWithFakeOCR(t *testing.T) {
+	api := useFakeOpenAI(t, `{"itens":[{"codigo":"1","descricao":"Parafuso","preco":10}]}`)
+
+	ocr.Register(&ocr.Fake{Texts: map[string]string{"page_1.png": "Código 1 Parafuso 10,00"}})
+	imagePath := filepath.Join(t.TempDir(), "page_1.png")
+	if err := os.WriteFile(imagePath, []byte("png"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	result := ProcessPage(context.Background(), "doc", PDFPage{Number: 1, ImagePath: imagePath}, ProcessingOptions{
+		Mode:           ExtractionModeOCR,
+		OCREngine:      "fake",
+		IncludeOCRText: true,
+	})
+
+	if result.Status != entities.PageStatusSuccess {
+		t.Fatalf("status = %s (%s)", result.Status, result.Error)
+	}
+	if result.OCREngine != "fake" {
+		t.Errorf("ocr_engine = %q", result.OCREngine)
+	}
+	if result.OCRText != "Código 1 Parafuso 10,00" {
+		t.Errorf("ocr_text = %q", result.OCRText)
+	}
+	if !strings.Contains(string(api.lastRequest(t)["messages"]), "Parafuso 10,00") {
+		t.Error("o texto do OCR não foi enviado ao modelo")
+	}
+	if result.Usage == nil || result.Usage.TotalTokens != 15 {
+		t.Errorf("usage = %+v", result.Usage)
+	}
+}
+
+func TestProcessPageCountsVisionOCRUsage(t *testing.T) {
+	useFakeOpenAI(t, `{"itens":[{"codigo":"1","descricao":"Parafuso","preco":10}]}`)
+
+	ocr.Register(ocr.NewVision(TranscribeImage))
+	imagePath := filepath.Join(t.TempDir(), "page_1.png")
+	if err := os.WriteFile(imagePath, []byte("png"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	result := ProcessPage(context.Background(), "doc", PDFPage{Number: 1, ImagePath: imagePath}, ProcessingOptions{
+		Mode:      ExtractionModeOCR,
+		OCREngine: "vision",
+	})
+
+	if result.Status != entities.PageStatusSuccess {
+		t.Fatalf("status = %s (%s)", result.Status, result.Error)
+	}
+	if result.Attempts != 2 {
+		t.Errorf("attempts = %d, esperado 2 (OCR e extração)", result.Attempts)
+	}
+	if result.Usage == nil || result.Usage.TotalTokens != 30 {
+		t.Errorf("usage = %+v, esperado o consumo das duas chamadas", result.Usage)
+	}
+}
@@ -2,155 +2,74 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"github.com/gofiber/fiber/v2/log"
-	"gosmart/config"
 	"gosmart/entities"
+	"gosmart/jsonrepair"
+	"gosmart/schema"
 	"image"
 	"image/png"
-	"io"
 	"net/http"
 	"time"
 )
 
 func GetAvailableModels() ([]entities.OpenAIModel, error) {
-	apiKey := config.GetEnv("OPENAI_API_KEY")
-	if apiKey == "" {
-		return nil, errors.New("OPENAI_API_KEY não definido no arquivo .env")
-	}
-
-	url := "https://api.openai.com/v1/models"
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("erro ao criar requisição: %w", err)
-	}
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("erro ao enviar requisição: %w", err)
-	}
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-			log.Error("erro ao fechar o corpo da resposta: ", err)
-		}
-	}(resp.Body)
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("requisição falhou: %s", string(body))
-	}
-
-	var response struct {
-		Data []entities.OpenAIModel `json:"data"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return nil, fmt.Errorf("erro ao processar resposta: %w", err)
-	}
-
-	return response.Data, nil
+	return AvailableModels.Get(context.Background())
 }
 
-func GetBestModel() (string, error) {
-	models, err := GetAvailableModels()
-	if err != nil {
-		return "", err
-	}
-
-	priorities := []string{"gpt-4", "gpt-4-turbo", "gpt-3.5-turbo"}
-
-	for _, priority := range priorities {
-		for _, model := range models {
-			if model.ID == priority {
-				return model.ID, nil
-			}
-		}
-	}
-
-	return "gpt-3.5-turbo", nil
+// ExtractionResult é o resultado do processamento de uma página pelo LLM.
+type ExtractionResult struct {
+	Data     map[string]interface{}
+	Model    string
+	Attempts int
+	Usage    entities.TokenUsage
+	// Schema é o esquema aplicado; SchemaErrors lista as violações que restaram após a nova tentativa.
+	Schema       string
+	SchemaErrors []string
+	Reprompted   bool
+	JSONRepairs  []string
+	// Truncated indica que a resposta final ainda foi cortada pelo limite de tokens, ou seja,
+	// que os dados podem estar incompletos. Chunks e Continuations contam as chamadas extras feitas para evitar isso.
+	Truncated     bool
+	Chunks        int
+	Continuations int
 }
 
-func GenerateText(prompt string) (string, error) {
-	apiKey := config.GetEnv("OPENAI_API_KEY")
-	apiURL := config.GetEnv("OPENAI_API_URL")
-	if apiKey == "" || apiURL == "" {
-		return "", errors.New("variáveis OPENAI_API_KEY ou OPENAI_API_URL não estão definidas")
-	}
+// callStats resume as tentativas e o consumo de tokens de uma chamada ao LLM.
+type callStats struct {
+	Attempts     int
+	Usage        entities.TokenUsage
+	Reprompted   bool
+	FinishReason string
+	// Repairs lista os reparos aplicados ao JSON da resposta (ver pacote jsonrepair).
+	Repairs []string
+}
 
-	model, err := GetBestModel()
+// GenerateText envia o prompt ao modelo de geração e devolve a resposta com o consumo de tokens.
+func GenerateText(ctx context.Context, prompt string) (string, entities.TokenUsage, error) {
+	model, err := SelectModel(ctx, OperationGeneration)
 	if err != nil {
-		return "", fmt.Errorf("erro ao obter o melhor modelo: %w", err)
+		return "", entities.TokenUsage{}, fmt.Errorf("erro ao selecionar o modelo: %w", err)
 	}
 
 	request := entities.ChatCompletionRequest{
 		Model: model,
-		Messages: []entities.ChatMessage{
+		Messages: []entities.ChatCompletionMessage{
 			{Role: "user", Content: prompt},
 		},
 	}
 
-	payload, err := json.Marshal(request)
-	if err != nil {
-		return "", fmt.Errorf("erro ao serializar o payload: %w", err)
-	}
-
-	req, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(payload))
-	if err != nil {
-		return "", fmt.Errorf("erro ao criar a requisição: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("erro ao enviar a requisição: %w", err)
-	}
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-			_ = fmt.Errorf("erro ao fechar o corpo da resposta: %w", err)
-		}
-	}(resp.Body)
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("erro ao ler a resposta: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("requisição falhou com status %d: %s", resp.StatusCode, string(body))
-	}
-
-	var response entities.ChatCompletionResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return "", fmt.Errorf("erro ao deserializar a resposta: %w", err)
-	}
-
-	if len(response.Choices) > 0 {
-		return response.Choices[0].Message.Content, nil
-	}
-
-	return "", errors.New("nenhuma resposta válida retornada")
+	content, stats, err := completeText(ctx, request)
+	return content, stats.Usage, err
 }
 
-func ExtractTextFromImage(img image.Image) (map[string]string, error) {
-	apiKey := config.GetEnv("OPENAI_API_KEY")
-	apiURL := config.GetEnv("OPENAI_API_URL")
-	if apiKey == "" || apiURL == "" {
-		return nil, fmt.Errorf("variáveis OPENAI_API_KEY ou OPENAI_API_URL não configuradas")
-	}
-
-	model, err := GetBestModel()
+func ExtractTextFromImage(ctx context.Context, img image.Image) (map[string]string, error) {
+	model, err := SelectModel(ctx, OperationVision)
 	if err != nil {
-		return nil, fmt.Errorf("erro ao obter o melhor modelo: %w", err)
+		return nil, fmt.Errorf("erro ao selecionar o modelo: %w", err)
 	}
 
 	var imgBuffer bytes.Buffer
@@ -168,82 +87,37 @@ Sempre responda em JSON no formato:
   'Campo2': 'Valor2'
 };
 `
-	requestBody := entities.ParsePdfRequest{
+	requestBody := entities.ChatCompletionRequest{
 		Model:       model,
 		MaxTokens:   4096,
-		Temperature: 0.2,
+		Temperature: temperature(0.2),
 		Messages: []entities.ChatCompletionMessage{
 			{
 				Role:    "system",
 				Content: "Você é um assistente que processa imagens relacionadas a documentos PDF.",
 			},
 			{
-				Role:    "user",
-				Content: fixedPrompt,
+				Role: "user",
+				Parts: []entities.ContentPart{
+					{Type: "text", Text: fixedPrompt},
+					imagePart("image/png", imgBuffer.Bytes()),
+				},
 			},
 		},
 	}
 
-	payload, err := json.Marshal(requestBody)
-	if err != nil {
-		return nil, fmt.Errorf("erro ao serializar requisição: %w", err)
-	}
-
-	req, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(payload))
-	if err != nil {
-		return nil, fmt.Errorf("erro ao criar requisição HTTP: %w", err)
-	}
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("erro ao enviar requisição: %w", err)
-	}
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-			log.Error("erro ao fechar o corpo da resposta: ", err)
-		}
-	}(resp.Body)
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("requisição falhou com status %d: %s", resp.StatusCode, string(body))
-	}
-
-	var response struct {
-		Choices []struct {
-			Message struct {
-				Content string `json:"content"`
-			} `json:"message"`
-		} `json:"choices"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return nil, fmt.Errorf("erro ao decodificar resposta: %w", err)
-	}
-
 	var extractedData map[string]string
-	if len(response.Choices) > 0 {
-		if err := json.Unmarshal([]byte(response.Choices[0].Message.Content), &extractedData); err != nil {
-			return nil, fmt.Errorf("erro ao parsear JSON retornado: %w", err)
-		}
+	if _, err := completeJSON(ctx, requestBody, &extractedData); err != nil {
+		return nil, err
 	}
 
 	return extractedData, nil
 }
 
-func ProcessPDFPage(pageContent []byte) (map[string]interface{}, error) {
-	apiKey := config.GetEnv("OPENAI_API_KEY")
-	apiURL := config.GetEnv("OPENAI_API_URL")
-	if apiKey == "" || apiURL == "" {
-		return nil, fmt.Errorf("variáveis OPENAI_API_KEY ou OPENAI_API_URL não configuradas")
-	}
-
-	model, err := GetBestModel()
+func ProcessPDFPage(ctx context.Context, pageContent []byte) (map[string]interface{}, error) {
+	model, err := SelectModel(ctx, OperationVision)
 	if err != nil {
-		return nil, fmt.Errorf("erro ao obter o melhor modelo: %w", err)
+		return nil, fmt.Errorf("erro ao selecionar o modelo: %w", err)
 	}
 
 	pageBase64 := base64.StdEncoding.EncodeToString(pageContent)
@@ -254,10 +128,10 @@ Extraia o texto contido na página e organize as informações relevantes em um
 Se não for possível entender o conteúdo, retorne um JSON vazio.
 Sempre responda no formato JSON.
 `
-	requestBody := entities.ParsePdfRequest{
+	requestBody := entities.ChatCompletionRequest{
 		Model:       model,
 		MaxTokens:   4096,
-		Temperature: 0.2,
+		Temperature: temperature(0.2),
 		Messages: []entities.ChatCompletionMessage{
 			{
 				Role:    "system",
@@ -270,155 +144,90 @@ Sempre responda no formato JSON.
 		},
 	}
 
-	payload, err := json.Marshal(requestBody)
-	if err != nil {
-		return nil, fmt.Errorf("erro ao serializar requisição: %w", err)
-	}
-
-	req, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(payload))
-	if err != nil {
-		return nil, fmt.Errorf("erro ao criar requisição HTTP: %w", err)
-	}
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("erro ao enviar requisição: %w", err)
-	}
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-			log.Error("erro ao fechar o corpo da resposta: ", err)
-		}
-	}(resp.Body)
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("requisição falhou com status %d: %s", resp.StatusCode, string(body))
-	}
-
-	var response struct {
-		Choices []struct {
-			Message struct {
-				Content string `json:"content"`
-			} `json:"message"`
-		} `json:"choices"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return nil, fmt.Errorf("erro ao decodificar resposta: %w", err)
-	}
-
 	var result map[string]interface{}
-	if len(response.Choices) > 0 {
-		if err := json.Unmarshal([]byte(response.Choices[0].Message.Content), &result); err != nil {
-			return nil, fmt.Errorf("erro ao parsear JSON retornado: %w", err)
-		}
+	if _, err := completeJSON(ctx, requestBody, &result); err != nil {
+		return nil, err
 	}
 
 	return result, nil
 }
 
-func ProcessImagePage(imageContent []byte) (map[string]interface{}, error) {
-	apiKey := config.GetEnv("OPENAI_API_KEY")
-	apiURL := config.GetEnv("OPENAI_API_URL")
-	if apiKey == "" || apiURL == "" {
-		return nil, fmt.Errorf("variáveis OPENAI_API_KEY ou OPENAI_API_URL não configuradas")
-	}
-
-	model, err := GetBestModel()
+// ProcessImagePage envia a imagem da página diretamente ao modelo de visão, sem passar pelo OCR local.
+// Com um esquema, a resposta é restrita e validada contra ele.
+func ProcessImagePage(ctx context.Context, imageContent []byte, extractionSchema *schema.Schema) (*ExtractionResult, error) {
+	model, err := SelectModel(ctx, OperationVision)
 	if err != nil {
-		return nil, fmt.Errorf("erro ao obter o melhor modelo: %w", err)
+		return nil, fmt.Errorf("erro ao selecionar o modelo: %w", err)
 	}
 
-	imageBase64 := base64.StdEncoding.EncodeToString(imageContent)
-
 	var imagePrompt = `
-Você receberá uma imagem em base64.
+Você receberá a imagem de uma página.
 Extraia o texto contido na imagem usando OCR e organize as informações relevantes em um objeto JSON.
 Se não for possível entender o conteúdo, retorne um JSON vazio.
 Sempre responda no formato JSON.
 `
-	requestBody := entities.ParsePdfRequest{
+	if extractionSchema != nil {
+		imagePrompt += schemaInstructions(extractionSchema)
+	}
+
+	requestBody := entities.ChatCompletionRequest{
 		Model:       model,
 		MaxTokens:   4096,
-		Temperature: 0.2,
+		Temperature: temperature(0.2),
 		Messages: []entities.ChatCompletionMessage{
 			{
 				Role:    "system",
 				Content: "Você é um assistente que processa imagens para extrair texto e informações úteis usando OCR.",
 			},
 			{
-				Role:    "user",
-				Content: imagePrompt + "\nImagem em base64:\n" + imageBase64,
+				Role: "user",
+				Parts: []entities.ContentPart{
+					{Type: "text", Text: imagePrompt},
+					imagePart(http.DetectContentType(imageContent), imageContent),
+				},
 			},
 		},
 	}
 
-	payload, err := json.Marshal(requestBody)
+	result, err := extract(ctx, model, requestBody, extractionSchema)
 	if err != nil {
-		return nil, fmt.Errorf("erro ao serializar requisição: %w", err)
+		return nil, err
 	}
+	// Não há como dividir a imagem; respostas cortadas são completadas por continuação
+	return continueExtraction(ctx, model, requestBody, extractionSchema, result)
+}
 
-	req, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(payload))
-	if err != nil {
-		return nil, fmt.Errorf("erro ao criar requisição HTTP: %w", err)
-	}
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-	req.Header.Set("Content-Type", "application/json")
+// ProcessExtractedText corrige o texto do OCR e o organiza em JSON. Com um esquema, as chaves
+// deixam de ser escolhidas pelo modelo e a resposta é validada contra ele. Respostas cortadas pelo
+// limite de tokens são refeitas em partes do texto alinhadas às linhas (ver extractTextInChunks).
+func ProcessExtractedText(ctx context.Context, text string, extractionSchema *schema.Schema) (*ExtractionResult, error) {
+	currentTime := time.Now()
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	model, err := SelectModel(ctx, OperationOCRCleanup)
 	if err != nil {
-		return nil, fmt.Errorf("erro ao enviar requisição: %w", err)
+		return nil, fmt.Errorf("erro ao selecionar o modelo: %w", err)
 	}
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-			log.Error("erro ao fechar o corpo da resposta: ", err)
-		}
-	}(resp.Body)
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("requisição falhou com status %d: %s", resp.StatusCode, string(body))
+	result, err := extractTextInChunks(ctx, model, text, "", extractionSchema, 0)
+	if err != nil {
+		return nil, err
 	}
 
-	var response struct {
-		Choices []struct {
-			Message struct {
-				Content string `json:"content"`
-			} `json:"message"`
-		} `json:"choices"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return nil, fmt.Errorf("erro ao decodificar resposta: %w", err)
+	jsonData, err := json.Marshal(result.Data)
+	if err != nil {
+		log.Error("erro ao serializar JSON: ", err)
 	}
 
-	var result map[string]interface{}
-	if len(response.Choices) > 0 {
-		if err := json.Unmarshal([]byte(response.Choices[0].Message.Content), &result); err != nil {
-			return nil, fmt.Errorf("erro ao parsear JSON retornado: %w", err)
-		}
-	}
+	processedTime := time.Since(currentTime)
+	log.Infof("Texto processado em %s\n", processedTime)
+	log.Infof("Texto processado: %s\n\n", string(jsonData))
 
 	return result, nil
 }
 
-func ProcessExtractedText(text string) (map[string]interface{}, error) {
-	currentTime := time.Now()
-	apiKey := config.GetEnv("OPENAI_API_KEY")
-	apiURL := config.GetEnv("OPENAI_API_URL")
-	if apiKey == "" || apiURL == "" {
-		return nil, fmt.Errorf("variáveis OPENAI_API_KEY ou OPENAI_API_URL não configuradas")
-	}
-
-	model, err := GetBestModel()
-	if err != nil {
-		return nil, fmt.Errorf("erro ao obter o melhor modelo: %w", err)
-	}
-
+// textExtractionRequest monta a requisição que corrige e estrutura um texto (ou parte dele).
+// header, quando informado, é a primeira linha do texto completo, repetida nas partes seguintes como contexto das colunas.
+func textExtractionRequest(model string, text string, header string, extractionSchema *schema.Schema) entities.ChatCompletionRequest {
 	prompt := fmt.Sprintf(`
     O seguinte texto foi extraído de uma imagem. Corrija erros de OCR e organize os dados em formato JSON 
     com as chaves identificadas e seus respectivos valores.
@@ -430,11 +239,21 @@ func ProcessExtractedText(text string) (map[string]interface{}, error) {
     TEXTO:
     %s
 `, text)
+	if header != "" {
+		prompt = fmt.Sprintf(`
+    O texto abaixo é a continuação de uma tabela. A primeira linha do texto original foi:
+    %s
+    Use-a apenas para identificar as colunas; não a inclua como item.
+`, header) + prompt
+	}
+	if extractionSchema != nil {
+		prompt += schemaInstructions(extractionSchema)
+	}
 
-	requestBody := entities.ParsePdfRequest{
+	return entities.ChatCompletionRequest{
 		Model:       model,
 		MaxTokens:   6144,
-		Temperature: 0.2,
+		Temperature: temperature(0.2),
 		Messages: []entities.ChatCompletionMessage{
 			{
 				Role:    "system",
@@ -446,62 +265,110 @@ func ProcessExtractedText(text string) (map[string]interface{}, error) {
 			},
 		},
 	}
+}
 
-	payload, err := json.Marshal(requestBody)
-	if err != nil {
-		return nil, fmt.Errorf("erro ao serializar requisição: %w", err)
+// extract executa a requisição de extração, com ou sem esquema, e monta o ExtractionResult.
+func extract(ctx context.Context, model string, request entities.ChatCompletionRequest, extractionSchema *schema.Schema) (*ExtractionResult, error) {
+	if extractionSchema == nil {
+		var data map[string]interface{}
+		stats, err := completeJSON(ctx, request, &data)
+		if err != nil {
+			return nil, err
+		}
+		return &ExtractionResult{
+			Data:        data,
+			Model:       model,
+			Attempts:    stats.Attempts,
+			Usage:       stats.Usage,
+			JSONRepairs: stats.Repairs,
+			Truncated:   stats.FinishReason == finishReasonLength,
+		}, nil
 	}
 
-	req, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(payload))
+	data, violations, stats, err := completeSchema(ctx, request, extractionSchema)
 	if err != nil {
-		return nil, fmt.Errorf("erro ao criar requisição HTTP: %w", err)
+		return nil, err
+	}
+	return &ExtractionResult{
+		Data:         data,
+		Model:        model,
+		Attempts:     stats.Attempts,
+		Usage:        stats.Usage,
+		Schema:       extractionSchema.Name,
+		SchemaErrors: violations,
+		Reprompted:   stats.Reprompted,
+		JSONRepairs:  stats.Repairs,
+		Truncated:    stats.FinishReason == finishReasonLength,
+	}, nil
+}
+
+// temperature devolve o ponteiro usado em ChatCompletionRequest.Temperature.
+func temperature(value float64) *float64 {
+	return &value
+}
+
+// imagePart monta uma parte de mensagem com a imagem embutida como data URI.
+func imagePart(mimeType string, data []byte) entities.ContentPart {
+	return entities.ContentPart{
+		Type: "image_url",
+		ImageURL: &entities.ImageURL{
+			URL: "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data),
+		},
 	}
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-	req.Header.Set("Content-Type", "application/json")
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+// completeText confere o orçamento de tokens do prompt e os tetos de gasto, envia a requisição e devolve o conteúdo da resposta.
+func completeText(ctx context.Context, request entities.ChatCompletionRequest) (string, callStats, error) {
+	estimated, err := fitBudget(&request)
 	if err != nil {
-		return nil, fmt.Errorf("erro ao enviar requisição: %w", err)
+		return "", callStats{}, err
 	}
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-			log.Error("erro ao fechar o corpo da resposta: ", err)
+	if Budgets != nil {
+		if err := Budgets.Check(ctx); err != nil {
+			return "", callStats{}, err
+		}
+	}
+	// Chamadas feitas no processamento de páginas aguardam o limite por chamador em vez de falhar
+	if scope := scopeFromContext(ctx); scope.DocumentID != "" && RateLimits != nil {
+		if err := RateLimits.Wait(ctx, RateLimitPageLLM, scope.Caller); err != nil {
+			return "", callStats{}, err
 		}
-	}(resp.Body)
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("requisição falhou com status %d: %s", resp.StatusCode, string(body))
 	}
 
-	var response struct {
-		Choices []struct {
-			Message struct {
-				Content string `json:"content"`
-			} `json:"message"`
-		} `json:"choices"`
+	response, err := OpenAIClient.ChatCompletion(ctx, request)
+	if err != nil {
+		return "", callStats{Attempts: AttemptsFromError(err)}, err
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return nil, fmt.Errorf("erro ao decodificar resposta: %w", err)
+
+	if Usage != nil {
+		Usage.Record(ctx, request.Model, response.Usage)
 	}
+	auditUsage(ctx, request.Model, response.Usage)
 
-	var result map[string]interface{}
-	if len(response.Choices) > 0 {
-		if err := json.Unmarshal([]byte(response.Choices[0].Message.Content), &result); err != nil {
-			return nil, fmt.Errorf("erro ao parsear JSON retornado: %w", err)
-		}
+	stats := callStats{Attempts: response.Attempts, Usage: response.Usage, FinishReason: response.Choices[0].FinishReason}
+	stats.Usage.EstimatedPromptTokens = estimated
+	message := response.Choices[0].Message
+	if message.Content == "" && len(message.ToolCalls) > 0 {
+		// Com tool calling, a resposta estruturada vem nos argumentos da função
+		return message.ToolCalls[0].Function.Arguments, stats, nil
 	}
+	return message.Content, stats, nil
+}
 
-	jsonData, err := json.Marshal(result)
+func completeJSON(ctx context.Context, request entities.ChatCompletionRequest, out interface{}) (callStats, error) {
+	content, stats, err := completeText(ctx, request)
 	if err != nil {
-		log.Error("erro ao serializar JSON: ", err)
+		return stats, err
 	}
 
-	processedTime := time.Since(currentTime)
-	log.Infof("Texto processado em %s\n", processedTime)
-	log.Infof("Texto processado: %s\n\n", string(jsonData))
+	repairs, err := jsonrepair.Unmarshal(content, stats.FinishReason == finishReasonLength, out)
+	if err != nil {
+		return stats, fmt.Errorf("erro ao parsear JSON retornado: %w", err)
+	}
+	if len(repairs) > 0 {
+		log.Warnf("JSON retornado precisou de reparos: %v", repairs)
+	}
+	stats.Repairs = repairs
 
-	return result, nil
+	return stats, nil
 }
@@ -0,0 +1,258 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"github.com/gofiber/fiber/v2/log"
+	"gosmart/config"
+	"gosmart/entities"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	pageQueueStream          = "gosmart:queue:pages"
+	pageQueueGroup           = "gosmart-workers"
+	defaultWorkerCount       = 2
+	defaultVisibilityTimeout = 10 * time.Minute
+	queueReadBlock           = 5 * time.Second
+)
+
+// pageTask é a unidade de trabalho da fila: uma página de um job.
+type pageTask struct {
+	JobID   string            `json:"job_id"`
+	Options ProcessingOptions `json:"options"`
+	Page    PDFPage           `json:"page"`
+}
+
+// QueueStats resume o estado da fila de páginas.
+type QueueStats struct {
+	Waiting    int64 `json:"waiting"`
+	InProgress int64 `json:"in_progress"`
+	ActiveJobs int64 `json:"active_jobs"`
+}
+
+// PageQueue é a fila global de páginas, implementada com um Redis Stream e um consumer group.
+// Uma página só é confirmada (XACK) depois que o resultado foi gravado; páginas de workers que
+// caíram são reivindicadas por outro worker após o visibility timeout, garantindo processamento at-least-once.
+type PageQueue struct {
+	visibilityTimeout time.Duration
+}
+
+var Pages *PageQueue
+
+// InitPageQueue cria o consumer group da fila, se ainda não existir.
+func InitPageQueue() {
+	visibilityTimeout := defaultVisibilityTimeout
+	if value := config.GetEnv("QUEUE_VISIBILITY_TIMEOUT"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			log.Warnf("QUEUE_VISIBILITY_TIMEOUT inválido (%s), usando %s", value, defaultVisibilityTimeout)
+		} else {
+			visibilityTimeout = parsed
+		}
+	}
+
+	Pages = &PageQueue{visibilityTimeout: visibilityTimeout}
+
+	if err := createPageQueueGroup(context.Background()); err != nil {
+		log.Error("erro ao criar consumer group da fila de páginas: ", err)
+	}
+}
+
+// createPageQueueGroup cria o stream e o consumer group. Um grupo já existente não é erro.
+func createPageQueueGroup(ctx context.Context) error {
+	err := RedisClient.XGroupCreateMkStream(ctx, pageQueueStream, pageQueueGroup, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+	return nil
+}
+
+// WorkerCountFromEnv devolve o número de workers configurado em WORKER_COUNT.
+func WorkerCountFromEnv() int {
+	if value := config.GetEnv("WORKER_COUNT"); value != "" {
+		count, err := strconv.Atoi(value)
+		if err == nil && count >= 0 {
+			return count
+		}
+		log.Warnf("WORKER_COUNT inválido (%s), usando %d", value, defaultWorkerCount)
+	}
+	return defaultWorkerCount
+}
+
+// Enqueue publica as páginas de um job na fila em uma única transação.
+func (q *PageQueue) Enqueue(ctx context.Context, jobID string, options ProcessingOptions, pages []PDFPage) error {
+	_, err := RedisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, page := range pages {
+			data, err := json.Marshal(pageTask{JobID: jobID, Options: options, Page: page})
+			if err != nil {
+				return fmt.Errorf("erro ao serializar tarefa: %w", err)
+			}
+			pipe.XAdd(ctx, &redis.XAddArgs{
+				Stream: pageQueueStream,
+				Values: map[string]interface{}{"task": data},
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("erro ao enfileirar páginas: %w", err)
+	}
+	return nil
+}
+
+// Stats devolve a profundidade da fila: páginas aguardando, em processamento e jobs ativos.
+func (q *PageQueue) Stats(ctx context.Context) (*QueueStats, error) {
+	length, err := RedisClient.XLen(ctx, pageQueueStream).Result()
+	if err != nil {
+		return nil, fmt.Errorf("erro ao consultar tamanho da fila: %w", err)
+	}
+	pending, err := RedisClient.XPending(ctx, pageQueueStream, pageQueueGroup).Result()
+	if err != nil {
+		return nil, fmt.Errorf("erro ao consultar páginas em processamento: %w", err)
+	}
+	activeJobs, err := RedisClient.SCard(ctx, activeJobsKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("erro ao consultar jobs ativos: %w", err)
+	}
+
+	return &QueueStats{
+		Waiting:    length - pending.Count,
+		InProgress: pending.Count,
+		ActiveJobs: activeJobs,
+	}, nil
+}
+
+// StartWorkers inicia count workers que consomem a fila até o contexto ser cancelado.
+// O WaitGroup devolvido é liberado quando todos os workers terminam a página em andamento.
+func (q *PageQueue) StartWorkers(ctx context.Context, count int) *sync.WaitGroup {
+	var wg sync.WaitGroup
+	hostname, _ := os.Hostname()
+
+	for i := 1; i <= count; i++ {
+		consumer := fmt.Sprintf("%s-%d-%d", hostname, os.Getpid(), i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx, consumer)
+		}()
+	}
+
+	log.Infof("%d worker(s) de páginas iniciados", count)
+	return &wg
+}
+
+func (q *PageQueue) work(ctx context.Context, consumer string) {
+	for ctx.Err() == nil {
+		message, ok, err := q.next(ctx, consumer)
+		if err != nil && strings.HasPrefix(err.Error(), "NOGROUP") {
+			// O grupo não existe se o Redis estava fora na inicialização ou perdeu os dados.
+			if err = createPageQueueGroup(ctx); err == nil {
+				log.Warn("consumer group da fila de páginas recriado")
+				continue
+			}
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Error("erro ao ler a fila de páginas: ", err)
+				_ = sleepContext(ctx, time.Second)
+			}
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		q.handle(message)
+	}
+}
+
+// next reivindica primeiro páginas abandonadas por outros workers e, se não houver, lê novas páginas.
+func (q *PageQueue) next(ctx context.Context, consumer string) (redis.XMessage, bool, error) {
+	claimed, _, err := RedisClient.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+		Stream:   pageQueueStream,
+		Group:    pageQueueGroup,
+		MinIdle:  q.visibilityTimeout,
+		Start:    "0-0",
+		Count:    1,
+		Consumer: consumer,
+	}).Result()
+	if err != nil {
+		return redis.XMessage{}, false, err
+	}
+	if len(claimed) > 0 {
+		log.Warnf("página %s reivindicada por %s após visibility timeout", claimed[0].ID, consumer)
+		return claimed[0], true, nil
+	}
+
+	streams, err := RedisClient.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    pageQueueGroup,
+		Consumer: consumer,
+		Streams:  []string{pageQueueStream, ">"},
+		Count:    1,
+		Block:    queueReadBlock,
+	}).Result()
+	if err == redis.Nil {
+		return redis.XMessage{}, false, nil
+	}
+	if err != nil {
+		return redis.XMessage{}, false, err
+	}
+	if len(streams) == 0 || len(streams[0].Messages) == 0 {
+		return redis.XMessage{}, false, nil
+	}
+
+	return streams[0].Messages[0], true, nil
+}
+
+// handle processa uma página e confirma a mensagem quando o resultado foi gravado ou a página foi
+// descartada. Em erros transitórios a mensagem fica pendente e é reivindicada após o visibility timeout.
+// O processamento usa um contexto próprio para que o encerramento do worker não interrompa a página em andamento.
+func (q *PageQueue) handle(message redis.XMessage) {
+	ctx := context.Background()
+
+	raw, _ := message.Values["task"].(string)
+	var task pageTask
+	if err := json.Unmarshal([]byte(raw), &task); err != nil {
+		log.Errorf("tarefa inválida na fila (%s): %v", message.ID, err)
+		q.ack(ctx, message.ID)
+		return
+	}
+
+	if err := q.process(ctx, task); err != nil {
+		log.Errorf("página %d do job %s não confirmada, será reprocessada: %v", task.Page.Number, task.JobID, err)
+		return
+	}
+	q.ack(ctx, message.ID)
+}
+
+// process executa a página e, em caso de pânico, grava uma falha no lugar do resultado.
+func (q *PageQueue) process(ctx context.Context, task pageTask) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("pânico ao processar página %d do job %s: %v", task.Page.Number, task.JobID, r)
+			err = Jobs.StorePageResult(ctx, entities.PageResult{
+				DocumentID: task.JobID,
+				Page:       task.Page.Number,
+				Status:     entities.PageStatusFailed,
+				Error:      "Erro interno ao processar a página",
+			})
+		}
+	}()
+
+	return Jobs.ProcessTask(ctx, task)
+}
+
+func (q *PageQueue) ack(ctx context.Context, id string) {
+	if err := RedisClient.XAck(ctx, pageQueueStream, pageQueueGroup, id).Err(); err != nil {
+		log.Error("erro ao confirmar página na fila: ", err)
+		return
+	}
+	RedisClient.XDel(ctx, pageQueueStream, id)
+}
@@ -0,0 +1,229 @@
+package services
+
+import (
+	"unicode"
+	"unicode/utf16"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// pdfFont converte os códigos das strings de texto de uma fonte em Unicode.
+// Sem /ToUnicode, fontes simples caem no Latin-1/WinAnsi; fontes compostas (Type0, como Identity-H)
+// usam índices de glifo que não têm relação com Unicode e viram U+FFFD, contando como ilegíveis.
+type pdfFont struct {
+	codeBytes int
+	composite bool
+	toUnicode map[uint32]string
+}
+
+// pageFonts carrega as fontes declaradas nos recursos da página, indexadas pelo nome usado no operador Tf.
+// Fontes que não podem ser lidas ficam de fora e são tratadas como fontes simples.
+func pageFonts(ctx *model.Context, page int) map[string]*pdfFont {
+	pageDict, _, inherited, err := ctx.PageDict(page, false)
+	if err != nil {
+		return nil
+	}
+
+	resources := inherited.Resources
+	if resources == nil {
+		if resources, err = ctx.DereferenceDict(pageDict["Resources"]); err != nil || resources == nil {
+			return nil
+		}
+	}
+	fontDicts, err := ctx.DereferenceDict(resources["Font"])
+	if err != nil || fontDicts == nil {
+		return nil
+	}
+
+	fonts := make(map[string]*pdfFont, len(fontDicts))
+	for name, entry := range fontDicts {
+		fontDict, err := ctx.DereferenceDict(entry)
+		if err != nil || fontDict == nil {
+			continue
+		}
+		fonts[name] = loadFont(ctx, fontDict)
+	}
+	return fonts
+}
+
+func loadFont(ctx *model.Context, fontDict types.Dict) *pdfFont {
+	font := &pdfFont{codeBytes: 1}
+	if subtype := fontDict.Subtype(); subtype != nil && *subtype == "Type0" {
+		font.composite = true
+		font.codeBytes = 2
+	}
+
+	entry, found := fontDict.Find("ToUnicode")
+	if !found {
+		return font
+	}
+	stream, _, err := ctx.DereferenceStreamDict(entry)
+	if err != nil || stream == nil {
+		return font
+	}
+	if err := stream.Decode(); err != nil {
+		return font
+	}
+
+	mapping, codeBytes := parseToUnicodeCMap(stream.Content)
+	if len(mapping) > 0 {
+		font.toUnicode = mapping
+		if codeBytes > 0 {
+			font.codeBytes = codeBytes
+		}
+	}
+	return font
+}
+
+// decode traduz os bytes de uma string de texto. Sem fonte conhecida, mantém a interpretação de decodePDFBytes.
+func (f *pdfFont) decode(data []byte) string {
+	if f == nil || (f.toUnicode == nil && !f.composite) {
+		return decodePDFBytes(data)
+	}
+
+	var out []rune
+	for i := 0; i < len(data); i += f.codeBytes {
+		var code uint32
+		for j := i; j < i+f.codeBytes && j < len(data); j++ {
+			code = code<<8 | uint32(data[j])
+		}
+		if text, ok := f.toUnicode[code]; ok {
+			out = append(out, []rune(text)...)
+		} else if !f.composite {
+			out = append(out, rune(code))
+		} else {
+			out = append(out, unicode.ReplacementChar)
+		}
+	}
+	return string(out)
+}
+
+// parseToUnicodeCMap lê as seções bfchar e bfrange de um CMap /ToUnicode e devolve o mapa de códigos
+// e o tamanho em bytes dos códigos, obtido de codespacerange (0 quando não informado).
+func parseToUnicodeCMap(content []byte) (map[uint32]string, int) {
+	tokens := cmapTokens(content)
+	mapping := map[uint32]string{}
+	codeBytes := 0
+
+	for i := 0; i < len(tokens); i++ {
+		switch tokens[i] {
+		case "begincodespacerange":
+			if i+1 < len(tokens) && isHexToken(tokens[i+1]) {
+				codeBytes = len(hexTokenBytes(tokens[i+1]))
+			}
+		case "beginbfchar":
+			for i += 1; i+1 < len(tokens) && tokens[i] != "endbfchar"; i += 2 {
+				if isHexToken(tokens[i]) && isHexToken(tokens[i+1]) {
+					mapping[hexCode(tokens[i])] = utf16Text(hexTokenBytes(tokens[i+1]))
+				}
+			}
+		case "beginbfrange":
+			for i += 1; i+2 < len(tokens) && tokens[i] != "endbfrange"; i += 3 {
+				if !isHexToken(tokens[i]) || !isHexToken(tokens[i+1]) {
+					continue
+				}
+				low, high := hexCode(tokens[i]), hexCode(tokens[i+1])
+				if high < low || high-low > 0xFFFF {
+					continue
+				}
+
+				if tokens[i+2] == "[" {
+					// <lo> <hi> [<dst1> <dst2> ...]: um destino por código
+					code := low
+					for i += 3; i < len(tokens) && tokens[i] != "]"; i++ {
+						if isHexToken(tokens[i]) && code <= high {
+							mapping[code] = utf16Text(hexTokenBytes(tokens[i]))
+							code++
+						}
+					}
+					i -= 2
+					continue
+				}
+
+				// <lo> <hi> <dst>: o último caractere de dst é incrementado a cada código
+				if !isHexToken(tokens[i+2]) {
+					continue
+				}
+				units := utf16Units(hexTokenBytes(tokens[i+2]))
+				if len(units) == 0 {
+					continue
+				}
+				for code := low; code <= high; code++ {
+					shifted := append([]uint16(nil), units...)
+					shifted[len(shifted)-1] += uint16(code - low)
+					mapping[code] = string(utf16.Decode(shifted))
+				}
+			}
+		}
+	}
+
+	return mapping, codeBytes
+}
+
+// cmapTokens separa o CMap em strings hexadecimais (mantidas com os delimitadores), colchetes e palavras.
+func cmapTokens(content []byte) []string {
+	var tokens []string
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case c == '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case c == '<' && i+1 < len(content) && content[i+1] == '<', c == '>' && i+1 < len(content) && content[i+1] == '>':
+			i += 2
+		case c == '<':
+			end := i + 1
+			for end < len(content) && content[end] != '>' {
+				end++
+			}
+			tokens = append(tokens, string(content[i:min(end+1, len(content))]))
+			i = end + 1
+		case c == '[' || c == ']':
+			tokens = append(tokens, string(c))
+			i++
+		case isDelimiter(c):
+			i++
+		default:
+			start := i
+			for i < len(content) && !isDelimiter(content[i]) {
+				i++
+			}
+			tokens = append(tokens, string(content[start:i]))
+		}
+	}
+	return tokens
+}
+
+func isHexToken(token string) bool {
+	return len(token) >= 2 && token[0] == '<' && token[len(token)-1] == '>'
+}
+
+func hexTokenBytes(token string) []byte {
+	data, _ := readHexString([]byte(token), 0)
+	return data
+}
+
+func hexCode(token string) uint32 {
+	var code uint32
+	for _, b := range hexTokenBytes(token) {
+		code = code<<8 | uint32(b)
+	}
+	return code
+}
+
+func utf16Units(data []byte) []uint16 {
+	units := make([]uint16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		units = append(units, uint16(data[i])<<8|uint16(data[i+1]))
+	}
+	return units
+}
+
+func utf16Text(data []byte) string {
+	if len(data) == 1 {
+		return string(rune(data[0]))
+	}
+	return string(utf16.Decode(utf16Units(data)))
+}
@@ -0,0 +1,303 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gosmart/entities"
+	"gosmart/ocr"
+	"gosmart/rasterizer"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	ExtractionModeOCR    = "ocr"
+	ExtractionModeVision = "vision"
+
+	ExtractionMethodTextLayer = "text_layer"
+
+	budgetExceededMessage = "O limite de gasto com o LLM foi atingido"
+)
+
+// PDFPage descreve como uma página será processada: pela camada de texto nativa (Text)
+// ou pela imagem rasterizada (ImagePath). Skipped marca as páginas sem texto fora de RASTERIZER_PAGES.
+type PDFPage struct {
+	Number    int    `json:"number"`
+	Text      string `json:"text,omitempty"`
+	ImagePath string `json:"image_path,omitempty"`
+	Skipped   bool   `json:"skipped,omitempty"`
+}
+
+// PreparePDF lê a camada de texto do PDF e rasteriza apenas as páginas que precisam de OCR e estão
+// em RASTERIZER_PAGES (todas, se vazio), gravando as imagens no diretório de trabalho do job.
+// As páginas são devolvidas em ordem.
+func PreparePDF(ctx context.Context, pdfPath string, workDir string) ([]PDFPage, error) {
+	pageTexts, err := ExtractTextLayer(pdfPath)
+	if err != nil {
+		log.Printf("Erro ao ler camada de texto do PDF, todas as páginas serão rasterizadas: %v", err)
+		pageTexts = nil
+	}
+
+	var pagesToRasterize []int
+	textLayerPages := map[int]bool{}
+	for i, text := range pageTexts {
+		switch {
+		case HasUsableText(text):
+			textLayerPages[i+1] = true
+		case rasterSelected(i + 1):
+			pagesToRasterize = append(pagesToRasterize, i+1)
+		}
+	}
+	if pageTexts == nil {
+		// Sem a camada de texto, o número de páginas é desconhecido: vale a seleção configurada
+		pagesToRasterize = DefaultRasterOptions.Pages
+	}
+
+	var images []rasterizer.Page
+	if pageTexts == nil || len(pagesToRasterize) > 0 {
+		images, err = convertPDFToImages(ctx, pdfPath, workDir, pagesToRasterize)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	pageCount := len(pageTexts)
+	imageFiles := make(map[int]string, len(images))
+	for _, image := range images {
+		imageFiles[image.Number] = image.Path
+		if image.Number > pageCount {
+			pageCount = image.Number
+		}
+	}
+
+	pages := make([]PDFPage, pageCount)
+	for number := 1; number <= pageCount; number++ {
+		pages[number-1] = PDFPage{Number: number, ImagePath: imageFiles[number]}
+		if textLayerPages[number] {
+			pages[number-1].Text = pageTexts[number-1]
+		} else if !rasterSelected(number) {
+			pages[number-1].Skipped = true
+		}
+	}
+
+	return pages, nil
+}
+
+// rasterSelected indica se a página está na seleção de RASTERIZER_PAGES; sem seleção, todas estão.
+func rasterSelected(number int) bool {
+	if len(DefaultRasterOptions.Pages) == 0 {
+		return true
+	}
+	for _, page := range DefaultRasterOptions.Pages {
+		if page == number {
+			return true
+		}
+	}
+	return false
+}
+
+// ProcessPage extrai os dados de uma página e devolve o resultado no formato da resposta de /process-pdf.
+// Todo resultado, inclusive de erro, carrega o documento e o número original da página.
+func ProcessPage(ctx context.Context, documentID string, page PDFPage, options ProcessingOptions) entities.PageResult {
+	started := time.Now()
+	result := entities.PageResult{
+		DocumentID: documentID,
+		Page:       page.Number,
+		Method:     options.Mode,
+	}
+	processPage(ctx, page, options, &result)
+	result.Timings.TotalMs = time.Since(started).Milliseconds()
+	return result
+}
+
+func processPage(ctx context.Context, page PDFPage, options ProcessingOptions, result *entities.PageResult) {
+	var extraction *ExtractionResult
+	var err error
+
+	cacheStatus := &entities.PageCache{}
+	var ocrStats callStats
+	defer func() {
+		if *cacheStatus != (entities.PageCache{}) {
+			result.Cache = cacheStatus
+		}
+		// Tentativas e tokens do OCR por LLM entram no total da página, mesmo quando a extração falha
+		if ocrStats.Attempts > 0 {
+			result.Attempts += ocrStats.Attempts
+			if result.Usage == nil {
+				result.Usage = &entities.TokenUsage{}
+			}
+			result.Usage.Add(ocrStats.Usage)
+		}
+	}()
+
+	switch {
+	case page.Skipped:
+		fail(result, "Página sem camada de texto fora do intervalo de RASTERIZER_PAGES")
+		return
+	case page.ImagePath == "" && page.Text == "":
+		fail(result, "Página não encontrada na conversão do PDF")
+		return
+	case page.ImagePath == "":
+		// Usa a camada de texto nativa do PDF, sem rasterizar nem aplicar OCR
+		result.Method = ExtractionMethodTextLayer
+		key := extractionCacheKey(ctx, OperationOCRCleanup, extractionInputText, contentHash([]byte(page.Text)), options.Schema)
+		extraction, err = timed(&result.Timings.LLMMs, func() (*ExtractionResult, error) {
+			return cachedExtraction(ctx, key, options, cacheStatus, func() (*ExtractionResult, error) {
+				return ProcessExtractedText(ctx, page.Text, options.Schema)
+			})
+		})
+	case options.Mode == ExtractionModeVision:
+		// Envia a imagem diretamente ao modelo de visão, sem Tesseract
+		imageContent, readErr := os.ReadFile(page.ImagePath)
+		if readErr != nil {
+			log.Printf("Erro ao ler a imagem %d: %v", page.Number, readErr)
+			fail(result, "Erro ao ler a imagem")
+			return
+		}
+		key := extractionCacheKey(ctx, OperationVision, extractionInputImage, contentHash(imageContent), options.Schema)
+		extraction, err = timed(&result.Timings.LLMMs, func() (*ExtractionResult, error) {
+			return cachedExtraction(ctx, key, options, cacheStatus, func() (*ExtractionResult, error) {
+				return ProcessImagePage(ctx, imageContent, options.Schema)
+			})
+		})
+	default:
+		// Extrai texto da imagem com o backend de OCR escolhido
+		engine, engineErr := ocr.Get(options.OCREngine)
+		if engineErr != nil {
+			log.Printf("Erro ao obter backend de OCR da página %d: %v", page.Number, engineErr)
+			fail(result, "Backend de OCR indisponível")
+			return
+		}
+		result.OCREngine = engine.Name()
+
+		imageContent, readErr := os.ReadFile(page.ImagePath)
+		if readErr != nil {
+			log.Printf("Erro ao ler a imagem %d: %v", page.Number, readErr)
+			fail(result, "Erro ao ler a imagem")
+			return
+		}
+
+		ocrStarted := time.Now()
+		ocrKey := ocrCacheKey(contentHash(imageContent), engine.Name(), options.OCROptions)
+		extractedText, ocrErr := cachedOCR(ctx, ocrKey, options, cacheStatus, func() (string, error) {
+			return engine.ExtractText(withOCRStats(ctx, &ocrStats), page.ImagePath, options.OCROptions)
+		})
+		result.Timings.OCRMs = time.Since(ocrStarted).Milliseconds()
+		if errors.Is(ocrErr, ErrBudgetExceeded) {
+			log.Printf("OCR da página %d recusado: %v", page.Number, ocrErr)
+			fail(result, budgetExceededMessage)
+			return
+		}
+		if ocrErr != nil {
+			log.Printf("Erro ao extrair texto da imagem %d: %v", page.Number, ocrErr)
+			fail(result, "Erro ao extrair texto da imagem")
+			return
+		}
+
+		log.Printf("Texto extraído da imagem %d: %s", page.Number, extractedText)
+		if options.IncludeOCRText {
+			result.OCRText = extractedText
+		}
+		if strings.TrimSpace(extractedText) == "" {
+			result.Warnings = append(result.Warnings, "O OCR não encontrou texto na página")
+		}
+
+		// Processa o texto com OpenAI; o mesmo texto reaproveita a extração, inclusive entre camada de texto e OCR
+		key := extractionCacheKey(ctx, OperationOCRCleanup, extractionInputText, contentHash([]byte(extractedText)), options.Schema)
+		extraction, err = timed(&result.Timings.LLMMs, func() (*ExtractionResult, error) {
+			return cachedExtraction(ctx, key, options, cacheStatus, func() (*ExtractionResult, error) {
+				return ProcessExtractedText(ctx, extractedText, options.Schema)
+			})
+		})
+	}
+
+	if errors.Is(err, ErrPromptTooLarge) {
+		log.Printf("Página %d recusada antes do envio: %v", page.Number, err)
+		fail(result, "A página excede o orçamento de tokens do modelo")
+		return
+	}
+	if errors.Is(err, ErrBudgetExceeded) {
+		log.Printf("Página %d recusada: %v", page.Number, err)
+		fail(result, budgetExceededMessage)
+		return
+	}
+	if err != nil {
+		log.Printf("Erro ao processar a página %d com OpenAI: %v", page.Number, err)
+		fail(result, "Erro ao processar a página com OpenAI")
+		result.Attempts = AttemptsFromError(err)
+		return
+	}
+
+	result.Status = entities.PageStatusSuccess
+	result.Model = extraction.Model
+	if cacheStatus.Extraction == entities.CacheHit {
+		// Sem chamada ao LLM: não há tentativas nem consumo a informar
+		log.Printf("Página %d reaproveitada do cache (%s)", page.Number, result.Method)
+	} else {
+		log.Printf("Página %d processada (%s) com %s em %d tentativa(s)", page.Number, result.Method, extraction.Model, extraction.Attempts)
+		usage := extraction.Usage
+		result.Attempts = extraction.Attempts
+		result.Usage = &usage
+	}
+	result.Data = extraction.Data
+	result.Schema = extraction.Schema
+	result.SchemaErrors = extraction.SchemaErrors
+	result.JSONRepairs = extraction.JSONRepairs
+	result.Complete = !extraction.Truncated
+	result.Chunks = extraction.Chunks
+	result.Continuations = extraction.Continuations
+	if extraction.Truncated {
+		result.Warnings = append(result.Warnings, "A resposta do modelo foi cortada pelo limite de tokens; os dados da página podem estar incompletos")
+	}
+	if len(extraction.JSONRepairs) > 0 {
+		result.Warnings = append(result.Warnings, "O JSON retornado pelo modelo precisou de reparos; veja json_repairs")
+	}
+	if extraction.Reprompted {
+		result.Warnings = append(result.Warnings, "O modelo recebeu um novo prompt com os erros de validação do esquema")
+	}
+	if len(extraction.SchemaErrors) > 0 {
+		result.Warnings = append(result.Warnings, "Os dados não estão totalmente conformes ao esquema; veja schema_errors")
+	}
+	if extraction.Attempts > 1 && !extraction.Reprompted {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("A OpenAI respondeu após %d tentativas", extraction.Attempts))
+	}
+	if Budgets != nil {
+		result.Warnings = append(result.Warnings, Budgets.Warnings(ctx)...)
+	}
+}
+
+func fail(result *entities.PageResult, message string) {
+	result.Status = entities.PageStatusFailed
+	result.Error = message
+}
+
+// timed executa fn e acumula a duração em milissegundos em elapsed.
+func timed(elapsed *int64, fn func() (*ExtractionResult, error)) (*ExtractionResult, error) {
+	started := time.Now()
+	extraction, err := fn()
+	*elapsed += time.Since(started).Milliseconds()
+	return extraction, err
+}
+
+// convertPDFToImages rasteriza as páginas indicadas (todas, quando pages é vazio) com o backend configurado
+// e devolve as imagens em ordem de página.
+func convertPDFToImages(ctx context.Context, pdfPath string, workDir string, pages []int) ([]rasterizer.Page, error) {
+	imagesOutputDir := filepath.Join(workDir, "images")
+	if err := os.MkdirAll(imagesOutputDir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("erro ao criar diretório para imagens: %w", err)
+	}
+
+	options := DefaultRasterOptions
+	options.Pages = pages
+
+	images, err := DefaultRasterizer.Rasterize(ctx, pdfPath, imagesOutputDir, options)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao converter PDF para imagens com %s: %w", DefaultRasterizer.Name(), err)
+	}
+
+	return images, nil
+}
@@ -0,0 +1,304 @@
+package services
+
+import (
+	"fmt"
+	"gosmart/config"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	defaultTextLayerMinChars = 50
+	minPlausibleWords        = 5
+)
+
+// ExtractTextLayer lê a camada de texto nativa de cada página do PDF com o pdfcpu.
+// O índice do slice corresponde à página - 1; páginas digitalizadas retornam texto vazio ou ilegível.
+func ExtractTextLayer(pdfPath string) ([]string, error) {
+	file, err := os.Open(pdfPath)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao abrir PDF: %w", err)
+	}
+	defer file.Close()
+
+	conf := model.NewDefaultConfiguration()
+	conf.ValidationMode = model.ValidationRelaxed
+
+	ctx, err := api.ReadContext(file, conf)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler PDF: %w", err)
+	}
+	if err := ctx.EnsurePageCount(); err != nil {
+		return nil, fmt.Errorf("erro ao contar páginas do PDF: %w", err)
+	}
+
+	pages := make([]string, ctx.PageCount)
+	for page := 1; page <= ctx.PageCount; page++ {
+		reader, err := pdfcpu.ExtractPageContent(ctx, page)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao ler conteúdo da página %d: %w", page, err)
+		}
+		content, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao ler conteúdo da página %d: %w", page, err)
+		}
+		pages[page-1] = textFromContentStream(content, pageFonts(ctx, page))
+	}
+
+	return pages, nil
+}
+
+// HasUsableText indica se o texto extraído da camada nativa é suficiente para dispensar o OCR.
+// Códigos sem mapeamento para Unicode (U+FFFD) contam como ilegíveis, e texto embaralhado por fontes
+// subset sem /ToUnicode, que passa pela contagem de caracteres, é recusado por plausibleWords.
+func HasUsableText(text string) bool {
+	minChars := defaultTextLayerMinChars
+	if value := config.GetEnv("PDF_TEXT_LAYER_MIN_CHARS"); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed > 0 {
+			minChars = parsed
+		}
+	}
+
+	var total, readable int
+	for _, r := range text {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		total++
+		if r == unicode.ReplacementChar {
+			continue
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			readable++
+		}
+	}
+
+	return readable >= minChars && float64(readable) >= 0.9*float64(total) && plausibleWords(text)
+}
+
+// plausibleWords confere se as palavras do texto parecem linguagem natural: nas fontes subset com
+// codificação própria as letras saem deslocadas ("SURGXWR" em vez de "PRODUTO"), gerando palavras sem
+// vogais ou com longas sequências de consoantes. Textos com poucas palavras não são avaliados.
+func plausibleWords(text string) bool {
+	var words, plausible int
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) }) {
+		if len([]rune(word)) < 4 {
+			continue
+		}
+		words++
+
+		hasVowel, consonants, longestRun := false, 0, 0
+		for _, r := range norm.NFD.String(strings.ToLower(word)) {
+			if unicode.Is(unicode.Mn, r) {
+				continue
+			}
+			if strings.ContainsRune("aeiouy", r) {
+				hasVowel = true
+				consonants = 0
+				continue
+			}
+			consonants++
+			longestRun = max(longestRun, consonants)
+		}
+		if hasVowel && longestRun < 5 {
+			plausible++
+		}
+	}
+
+	return words < minPlausibleWords || float64(plausible) >= 0.8*float64(words)
+}
+
+// textFromContentStream percorre os operadores de texto (Tj, TJ, ' e ") do conteúdo da página
+// e monta o texto, quebrando linha nos operadores de posicionamento. As strings são decodificadas
+// com a fonte selecionada pelo último Tf (ver pdfFont); fonts pode ser nil.
+func textFromContentStream(content []byte, fonts map[string]*pdfFont) string {
+	var out strings.Builder
+	var pending []string
+	var font *pdfFont
+	var lastName string
+
+	flush := func() {
+		for _, s := range pending {
+			out.WriteString(s)
+		}
+		pending = pending[:0]
+	}
+
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case c == '(':
+			data, next := readLiteralString(content, i)
+			pending = append(pending, font.decode(data))
+			i = next
+		case c == '<' && i+1 < len(content) && content[i+1] == '<':
+			i += 2
+		case c == '<':
+			data, next := readHexString(content, i)
+			pending = append(pending, font.decode(data))
+			i = next
+		case c == '/':
+			i++
+			start := i
+			for i < len(content) && !isDelimiter(content[i]) {
+				i++
+			}
+			lastName = string(content[start:i])
+		case c == '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case c == '-' || (c >= '0' && c <= '9') || c == '.':
+			start := i
+			for i < len(content) && (content[i] == '-' || content[i] == '.' || (content[i] >= '0' && content[i] <= '9')) {
+				i++
+			}
+			// Deslocamentos grandes dentro de TJ costumam representar espaços entre palavras.
+			if value, err := strconv.ParseFloat(string(content[start:i]), 64); err == nil && value < -200 && len(pending) > 0 {
+				pending = append(pending, " ")
+			}
+		case isOperatorChar(c):
+			start := i
+			for i < len(content) && isOperatorChar(content[i]) {
+				i++
+			}
+			switch string(content[start:i]) {
+			case "ID":
+				// Dados binários de imagem inline vão até o operador EI.
+				if end := strings.Index(string(content[i:]), "EI"); end >= 0 {
+					i += end + 2
+				} else {
+					i = len(content)
+				}
+				pending = pending[:0]
+			case "Tf":
+				font = fonts[lastName]
+				pending = pending[:0]
+			case "Tj", "TJ":
+				flush()
+			case "'", "\"":
+				out.WriteString("\n")
+				flush()
+			case "Td", "TD", "T*", "Tm", "ET":
+				if out.Len() > 0 && !strings.HasSuffix(out.String(), "\n") {
+					out.WriteString("\n")
+				}
+				pending = pending[:0]
+			default:
+				pending = pending[:0]
+			}
+		default:
+			i++
+		}
+	}
+
+	return strings.TrimSpace(out.String())
+}
+
+func isOperatorChar(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '*' || c == '\'' || c == '"'
+}
+
+func isDelimiter(c byte) bool {
+	return strings.IndexByte(" \t\r\n\f()<>[]{}/%", c) >= 0
+}
+
+func readLiteralString(content []byte, start int) ([]byte, int) {
+	var out []byte
+	depth := 0
+	i := start
+	for i < len(content) {
+		c := content[i]
+		switch {
+		case c == '\\' && i+1 < len(content):
+			i++
+			switch e := content[i]; e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b', 'f':
+			case '\r', '\n':
+			default:
+				if e >= '0' && e <= '7' {
+					end := i
+					for end < len(content) && end < i+3 && content[end] >= '0' && content[end] <= '7' {
+						end++
+					}
+					value, _ := strconv.ParseUint(string(content[i:end]), 8, 8)
+					out = append(out, byte(value))
+					i = end - 1
+				} else {
+					out = append(out, e)
+				}
+			}
+		case c == '(':
+			if depth > 0 {
+				out = append(out, c)
+			}
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return out, i + 1
+			}
+			out = append(out, c)
+		default:
+			out = append(out, c)
+		}
+		i++
+	}
+	return out, i
+}
+
+func readHexString(content []byte, start int) ([]byte, int) {
+	end := start + 1
+	for end < len(content) && content[end] != '>' {
+		end++
+	}
+
+	var digits []byte
+	for _, c := range content[start+1 : end] {
+		if (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F') {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+
+	out := make([]byte, 0, len(digits)/2)
+	for i := 0; i < len(digits); i += 2 {
+		value, _ := strconv.ParseUint(string(digits[i:i+2]), 16, 8)
+		out = append(out, byte(value))
+	}
+
+	return out, end + 1
+}
+
+// decodePDFBytes interpreta os bytes como UTF-16BE (com BOM) ou como Latin-1/WinAnsi.
+func decodePDFBytes(data []byte) string {
+	if len(data) >= 2 && data[0] == 0xFE && data[1] == 0xFF {
+		var runes []rune
+		for i := 2; i+1 < len(data); i += 2 {
+			runes = append(runes, rune(data[i])<<8|rune(data[i+1]))
+		}
+		return string(runes)
+	}
+
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestPDF grava um PDF de uma página com o conteúdo e a fonte /F1 informados.
+// toUnicode vazio omite o CMap da fonte.
+func writeTestPDF(t *testing.T, fontDict string, toUnicode string, content string) string {
+	t.Helper()
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 4 0 R >> >> /Contents 5 0 R >>",
+		fontDict,
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+	}
+	if toUnicode != "" {
+		objects = append(objects, fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(toUnicode), toUnicode))
+	}
+
+	var pdf strings.Builder
+	pdf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = pdf.Len()
+		fmt.Fprintf(&pdf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := pdf.Len()
+	fmt.Fprintf(&pdf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&pdf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&pdf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	path := filepath.Join(t.TempDir(), "test.pdf")
+	if err := os.WriteFile(path, []byte(pdf.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const identityFont = "<< /Type /Font /Subtype /Type0 /BaseFont /ABCDEF+Arial /Encoding /Identity-H /DescendantFonts [] %s>>"
+
+// CMap com os glifos 1 a 5 mapeados para "Preço" e o glifo 6 para o espaço, usando bfchar e as duas formas de bfrange.
+const testToUnicode = `/CIDInit /ProcSet findresource begin
+12 dict begin
+begincmap
+1 begincodespacerange
+<0000> <FFFF>
+endcodespacerange
+2 beginbfchar
+<0005> <00E7>
+<0006> <0020>
+endbfchar
+3 beginbfrange
+<0001> <0001> <0050>
+<0002> <0002> <0072>
+<0003> <0004> [<0065> <006F>]
+endbfrange
+endcmap
+CMapName currentdict /CMap defineresource pop
+end
+end`
+
+func TestExtractTextLayerDecodesToUnicode(t *testing.T) {
+	content := "BT /F1 12 Tf 72 700 Td <00010002000300050004> Tj <0006> Tj <00010002000300050004> Tj ET"
+	path := writeTestPDF(t, fmt.Sprintf(identityFont, "/ToUnicode 6 0 R "), testToUnicode, content)
+
+	pages, err := ExtractTextLayer(path)
+	if err != nil {
+		t.Fatalf("ExtractTextLayer: %v", err)
+	}
+	if len(pages) != 1 || pages[0] != "Preço Preço" {
+		t.Errorf("texto = %q, esperado %q", pages, "Preço Preço")
+	}
+}
+
+func TestExtractTextLayerWithoutToUnicodeIsUnusable(t *testing.T) {
+	content := "BT /F1 12 Tf 72 700 Td <" + strings.Repeat("0031", 60) + "> Tj ET"
+	path := writeTestPDF(t, fmt.Sprintf(identityFont, ""), "", content)
+
+	pages, err := ExtractTextLayer(path)
+	if err != nil {
+		t.Fatalf("ExtractTextLayer: %v", err)
+	}
+	if len(pages) != 1 || !strings.ContainsRune(pages[0], '�') {
+		t.Fatalf("texto = %q, esperado U+FFFD para glifos sem mapeamento", pages)
+	}
+	if HasUsableText(pages[0]) {
+		t.Error("texto de fonte Identity-H sem ToUnicode aceito como utilizável")
+	}
+}
+
+func TestHasUsableText(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want bool
+	}{
+		{
+			name: "tabela legível",
+			text: "Código Descrição Unidade Preço\n1001 Parafuso sextavado galvanizado M8 UN 1,25\n1002 Porca sextavada M8 UN 0,35",
+			want: true,
+		},
+		{
+			name: "fonte subset com letras deslocadas",
+			text: "+HOOR:RUOG SURGXWR FRGLJR GHVFULFDR TXDQWLGDGH XQLWDULR YDORU WRWDO IRUQHFHGRU",
+			want: false,
+		},
+		{
+			name: "glifos sem mapeamento",
+			text: strings.Repeat("�", 80),
+			want: false,
+		},
+		{
+			name: "texto curto",
+			text: "Página 1",
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HasUsableText(tt.text); got != tt.want {
+				t.Errorf("HasUsableText(%q) = %v, esperado %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreparePDFSkipsPagesOutsideRasterSelection(t *testing.T) {
+	previous := DefaultRasterOptions
+	DefaultRasterOptions.Pages = []int{2}
+	t.Cleanup(func() { DefaultRasterOptions = previous })
+
+	// Página única sem texto utilizável e fora da seleção: não é rasterizada
+	content := "BT /F1 12 Tf 72 700 Td <" + strings.Repeat("0031", 60) + "> Tj ET"
+	path := writeTestPDF(t, fmt.Sprintf(identityFont, ""), "", content)
+
+	pages, err := PreparePDF(context.Background(), path, t.TempDir())
+	if err != nil {
+		t.Fatalf("PreparePDF: %v", err)
+	}
+	if len(pages) != 1 || !pages[0].Skipped || pages[0].ImagePath != "" {
+		t.Fatalf("páginas = %+v, esperado a página 1 ignorada", pages)
+	}
+
+	result := ProcessPage(context.Background(), "doc", pages[0], ProcessingOptions{})
+	if !result.Failed() || !strings.Contains(result.Error, "RASTERIZER_PAGES") {
+		t.Errorf("resultado = %s %q, esperado falha por RASTERIZER_PAGES", result.Status, result.Error)
+	}
+}
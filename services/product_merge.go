@@ -0,0 +1,342 @@
+package services
+
+import (
+	"fmt"
+	"gosmart/entities"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// identifierPrefixes identifica, pelo nome normalizado, a coluna que distingue um produto de outro.
+var identifierPrefixes = []string{"cod", "sku", "ref", "ean", "gtin", "id"}
+
+// MergeProducts consolida as tabelas de produtos extraídas página a página em uma única lista.
+// O esquema de colunas detectado nas primeiras páginas é levado adiante: chaves escritas de outra forma são
+// alinhadas a ele, e linhas sem chaves (listas de valores) seguem a ordem do cabeçalho em lista. Sem esse
+// cabeçalho, elas vão para colunas genéricas, e nunca para colunas vindas de objetos, cuja ordem original se
+// perde na decodificação. Cabeçalhos repetidos são descartados e, na virada de página, linhas repetidas são
+// removidas e linhas quebradas são unidas.
+// Devolve nil quando nenhuma página contém uma tabela.
+func MergeProducts(results []*entities.PageResult) *entities.ProductList {
+	pages := make([]*entities.PageResult, 0, len(results))
+	for _, result := range results {
+		if result != nil && !result.Failed() && result.Data != nil {
+			pages = append(pages, result)
+		}
+	}
+	sort.Slice(pages, func(i, j int) bool { return pages[i].Page < pages[j].Page })
+
+	merger := &productMerger{
+		list:    &entities.ProductList{Columns: []string{}, Products: []entities.Product{}},
+		columns: map[string]int{},
+	}
+	found := false
+	for _, page := range pages {
+		rows := findRows(page.Data)
+		if rows == nil {
+			continue
+		}
+		found = true
+		merger.addPage(page.Page, rows)
+	}
+
+	if !found {
+		return nil
+	}
+	return merger.list
+}
+
+type productMerger struct {
+	list      *entities.ProductList
+	columns   map[string]int // nome normalizado -> posição em list.Columns
+	positions []string       // colunas na ordem da tabela, para as linhas em lista
+}
+
+func (m *productMerger) addPage(page int, rows []interface{}) {
+	first := true
+	for _, raw := range rows {
+		known := len(m.list.Columns)
+		fields, ok := m.toFields(raw)
+		if !ok || len(fields) == 0 {
+			continue
+		}
+
+		if first && len(m.list.Products) > 0 {
+			previous := &m.list.Products[len(m.list.Products)-1]
+			if lastPage(previous) != page {
+				switch {
+				case sameFields(previous.Fields, fields):
+					previous.Pages = append(previous.Pages, page)
+					m.list.RemovedDuplicates++
+					first = false
+					continue
+				case m.continues(previous.Fields, fields, known):
+					m.join(previous.Fields, fields)
+					previous.Pages = append(previous.Pages, page)
+					m.list.MergedRows++
+					first = false
+					continue
+				}
+			}
+		}
+		first = false
+
+		m.list.Products = append(m.list.Products, entities.Product{Pages: []int{page}, Fields: fields})
+	}
+}
+
+// toFields converte uma linha (objeto ou lista de valores) em campos nomeados pelo esquema.
+// Devolve false para linhas de cabeçalho, que são descartadas.
+func (m *productMerger) toFields(raw interface{}) (map[string]interface{}, bool) {
+	switch row := raw.(type) {
+	case []interface{}:
+		// Sem esquema ainda, uma lista só de textos é o cabeçalho da tabela
+		if (len(m.list.Columns) == 0 && allStrings(row)) || m.isHeader(row) {
+			m.header(row)
+			m.list.RemovedHeaders++
+			return nil, false
+		}
+
+		fields := map[string]interface{}{}
+		for i, value := range row {
+			if isEmptyValue(value) {
+				continue
+			}
+			fields[m.positional(i)] = value
+		}
+		return fields, true
+	case map[string]interface{}:
+		keys := make([]string, 0, len(row))
+		for key := range row {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		values := make([]interface{}, 0, len(row))
+		fields := map[string]interface{}{}
+		for _, key := range keys {
+			value := row[key]
+			values = append(values, value)
+			if isEmptyValue(value) {
+				continue
+			}
+			if index, err := strconv.Atoi(key); err == nil && index >= 0 {
+				fields[m.positional(index)] = value
+			} else {
+				fields[m.column(key)] = value
+			}
+		}
+		if m.isHeader(values) {
+			m.list.RemovedHeaders++
+			return nil, false
+		}
+		return fields, true
+	default:
+		return nil, false
+	}
+}
+
+// column devolve o nome de exibição da coluna, registrando-a no esquema se ainda não existir.
+func (m *productMerger) column(name string) string {
+	key := normalizeKey(name)
+	if index, ok := m.columns[key]; ok {
+		return m.list.Columns[index]
+	}
+	m.columns[key] = len(m.list.Columns)
+	m.list.Columns = append(m.list.Columns, strings.TrimSpace(name))
+	return m.list.Columns[len(m.list.Columns)-1]
+}
+
+// header registra a ordem das colunas do primeiro cabeçalho em lista, que passa a valer para as linhas em lista.
+func (m *productMerger) header(row []interface{}) {
+	if len(m.positions) > 0 {
+		return
+	}
+	for _, value := range row {
+		if isEmptyValue(value) {
+			m.positional(len(m.positions))
+			continue
+		}
+		m.positions = append(m.positions, m.column(fmt.Sprint(value)))
+	}
+}
+
+// positional devolve a coluna na posição index da tabela, criando colunas genéricas se faltarem.
+func (m *productMerger) positional(index int) string {
+	for len(m.positions) <= index {
+		m.positions = append(m.positions, m.column(fmt.Sprintf("coluna_%d", len(m.positions)+1)))
+	}
+	return m.positions[index]
+}
+
+// isHeader indica se os valores repetem os nomes das colunas, como um cabeçalho repetido no topo da página.
+func (m *productMerger) isHeader(values []interface{}) bool {
+	if len(m.list.Columns) == 0 {
+		return false
+	}
+
+	filled, matches := 0, 0
+	for _, value := range values {
+		if isEmptyValue(value) {
+			continue
+		}
+		filled++
+		text, ok := value.(string)
+		if !ok {
+			continue
+		}
+		if _, known := m.columns[normalizeKey(text)]; known {
+			matches++
+		}
+	}
+	return filled > 0 && matches*2 > filled
+}
+
+// identifier devolve a coluna que identifica o produto (código, SKU, referência...), ou a primeira coluna.
+func (m *productMerger) identifier() string {
+	for _, column := range m.list.Columns {
+		key := normalizeKey(column)
+		for _, prefix := range identifierPrefixes {
+			if strings.HasPrefix(key, prefix) {
+				return column
+			}
+		}
+	}
+	if len(m.list.Columns) > 0 {
+		return m.list.Columns[0]
+	}
+	return ""
+}
+
+// continues indica se next é a continuação de previous, quebrada na virada de página: next completa
+// colunas que faltam em previous e nenhum dos seus valores conflita com os de previous. A única diferença
+// aceita é um texto continuado (ex.: descrição quebrada) em uma linha sem identificador próprio.
+// known é o número de colunas do esquema antes de next: colunas criadas pela própria next não faltavam
+// em previous, e next com elas é outra linha.
+func (m *productMerger) continues(previous, next map[string]interface{}, known int) bool {
+	_, nextHasID := next[m.identifier()]
+
+	missing := false
+	for column, value := range next {
+		if m.columns[normalizeKey(column)] >= known {
+			return false
+		}
+		existing, ok := previous[column]
+		if !ok {
+			missing = true
+			continue
+		}
+		if fmt.Sprint(existing) == fmt.Sprint(value) {
+			continue
+		}
+		_, existingIsText := existing.(string)
+		_, valueIsText := value.(string)
+		if nextHasID || !existingIsText || !valueIsText {
+			return false
+		}
+	}
+	return missing && len(previous) < known
+}
+
+// join completa previous com os campos de next. Valores presentes nas duas partes e diferentes são
+// concatenados, de modo que nenhum valor extraído se perca.
+func (m *productMerger) join(previous, next map[string]interface{}) {
+	for column, value := range next {
+		existing, ok := previous[column]
+		if !ok {
+			previous[column] = value
+			continue
+		}
+		existingText, valueText := fmt.Sprint(existing), fmt.Sprint(value)
+		if existingText != valueText {
+			previous[column] = strings.TrimSpace(existingText + " " + valueText)
+		}
+	}
+}
+
+// findRows procura nos dados da página a maior lista cujos itens são objetos ou listas de valores.
+func findRows(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		if len(v) == 0 {
+			return nil
+		}
+		for _, item := range v {
+			switch item.(type) {
+			case map[string]interface{}, []interface{}:
+			default:
+				return nil
+			}
+		}
+		return v
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		var best []interface{}
+		for _, key := range keys {
+			if rows := findRows(v[key]); len(rows) > len(best) {
+				best = rows
+			}
+		}
+		return best
+	default:
+		return nil
+	}
+}
+
+func lastPage(product *entities.Product) int {
+	return product.Pages[len(product.Pages)-1]
+}
+
+func sameFields(a, b map[string]interface{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for column, value := range a {
+		other, ok := b[column]
+		if !ok || fmt.Sprint(value) != fmt.Sprint(other) {
+			return false
+		}
+	}
+	return true
+}
+
+func allStrings(values []interface{}) bool {
+	for _, value := range values {
+		if _, ok := value.(string); !ok {
+			return false
+		}
+	}
+	return len(values) > 0
+}
+
+func isEmptyValue(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	text, ok := value.(string)
+	return ok && strings.TrimSpace(text) == ""
+}
+
+// normalizeKey compara nomes de colunas ignorando maiúsculas, acentos, espaços e pontuação,
+// de modo que "Código", "codigo" e "COD_IGO" sejam a mesma coluna.
+func normalizeKey(name string) string {
+	var out strings.Builder
+	for _, r := range norm.NFD.String(name) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			out.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"encoding/json"
+	"gosmart/entities"
+	"reflect"
+	"testing"
+)
+
+func TestMergeProducts(t *testing.T) {
+	tests := []struct {
+		name  string
+		pages []string
+		want  *entities.ProductList
+	}{
+		{
+			name: "linha quebrada na virada de página",
+			pages: []string{
+				`{"itens": [["Código", "Descrição", "Unidade", "Preço"], ["1001", "Parafuso", "UN", 1.25], ["1002", "Porca sextavada", "UN"]]}`,
+				`{"itens": [["Código", "Descrição", "Unidade", "Preço"], [null, "galvanizada", "", 0.35], ["1003", "Arruela", "UN", 0.1]]}`,
+			},
+			want: &entities.ProductList{
+				Columns: []string{"Código", "Descrição", "Unidade", "Preço"},
+				Products: []entities.Product{
+					{Pages: []int{1}, Fields: map[string]interface{}{"Código": "1001", "Descrição": "Parafuso", "Unidade": "UN", "Preço": 1.25}},
+					{Pages: []int{1, 2}, Fields: map[string]interface{}{"Código": "1002", "Descrição": "Porca sextavada galvanizada", "Unidade": "UN", "Preço": 0.35}},
+					{Pages: []int{2}, Fields: map[string]interface{}{"Código": "1003", "Descrição": "Arruela", "Unidade": "UN", "Preço": 0.1}},
+				},
+				MergedRows:     1,
+				RemovedHeaders: 2,
+			},
+		},
+		{
+			name: "linha repetida na virada de página",
+			pages: []string{
+				`{"itens": [{"codigo": "1001", "preco": 1.25}]}`,
+				`{"itens": [{"Código": "1001", "Preço": 1.25}, {"Código": "1002", "Preço": 0.35}]}`,
+			},
+			want: &entities.ProductList{
+				Columns: []string{"codigo", "preco"},
+				Products: []entities.Product{
+					{Pages: []int{1, 2}, Fields: map[string]interface{}{"codigo": "1001", "preco": 1.25}},
+					{Pages: []int{2}, Fields: map[string]interface{}{"codigo": "1002", "preco": 0.35}},
+				},
+				RemovedDuplicates: 1,
+			},
+		},
+		{
+			name: "linha com identificador próprio não é unida",
+			pages: []string{
+				`{"itens": [["Código", "Descrição", "Preço"], ["1001", "Parafuso"]]}`,
+				`{"itens": [["1002", null, 0.35]]}`,
+			},
+			want: &entities.ProductList{
+				Columns: []string{"Código", "Descrição", "Preço"},
+				Products: []entities.Product{
+					{Pages: []int{1}, Fields: map[string]interface{}{"Código": "1001", "Descrição": "Parafuso"}},
+					{Pages: []int{2}, Fields: map[string]interface{}{"Código": "1002", "Preço": 0.35}},
+				},
+				RemovedHeaders: 1,
+			},
+		},
+		{
+			name: "valor numérico conflitante não é unido",
+			pages: []string{
+				`{"itens": [["Código", "Unidade", "Preço"], ["1001", null, 1.25]]}`,
+				`{"itens": [[null, "UN", 1.3]]}`,
+			},
+			want: &entities.ProductList{
+				Columns: []string{"Código", "Unidade", "Preço"},
+				Products: []entities.Product{
+					{Pages: []int{1}, Fields: map[string]interface{}{"Código": "1001", "Preço": 1.25}},
+					{Pages: []int{2}, Fields: map[string]interface{}{"Unidade": "UN", "Preço": 1.3}},
+				},
+				RemovedHeaders: 1,
+			},
+		},
+		{
+			name: "linhas em lista seguem o cabeçalho repetido, não a ordem das chaves",
+			pages: []string{
+				`{"itens": [{"codigo": "1001", "descricao": "Parafuso", "unidade": "UN", "preco": 1.25}]}`,
+				`{"itens": [["Código", "Descrição", "Unidade", "Preço"], ["1002", "Porca", "CX", 0.35]]}`,
+			},
+			want: &entities.ProductList{
+				Columns: []string{"codigo", "descricao", "preco", "unidade"},
+				Products: []entities.Product{
+					{Pages: []int{1}, Fields: map[string]interface{}{"codigo": "1001", "descricao": "Parafuso", "unidade": "UN", "preco": 1.25}},
+					{Pages: []int{2}, Fields: map[string]interface{}{"codigo": "1002", "descricao": "Porca", "unidade": "CX", "preco": 0.35}},
+				},
+				RemovedHeaders: 1,
+			},
+		},
+		{
+			name: "linhas em lista sem cabeçalho não usam colunas de objetos",
+			pages: []string{
+				`{"itens": [{"codigo": "1001", "unidade": "UN", "preco": 1.25}]}`,
+				`{"itens": [["1002", "CX", 0.35]]}`,
+			},
+			want: &entities.ProductList{
+				Columns: []string{"codigo", "preco", "unidade", "coluna_1", "coluna_2", "coluna_3"},
+				Products: []entities.Product{
+					{Pages: []int{1}, Fields: map[string]interface{}{"codigo": "1001", "unidade": "UN", "preco": 1.25}},
+					{Pages: []int{2}, Fields: map[string]interface{}{"coluna_1": "1002", "coluna_2": "CX", "coluna_3": 0.35}},
+				},
+			},
+		},
+		{
+			name:  "página sem tabela",
+			pages: []string{`{"observacao": "sem produtos"}`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := make([]*entities.PageResult, 0, len(tt.pages))
+			for i, page := range tt.pages {
+				var data map[string]interface{}
+				if err := json.Unmarshal([]byte(page), &data); err != nil {
+					t.Fatal(err)
+				}
+				results = append(results, &entities.PageResult{Page: i + 1, Status: entities.PageStatusSuccess, Data: data})
+			}
+
+			got := MergeProducts(results)
+			if !reflect.DeepEqual(got, tt.want) {
+				gotJSON, _ := json.Marshal(got)
+				wantJSON, _ := json.Marshal(tt.want)
+				t.Errorf("MergeProducts = %s\nesperado %s", gotJSON, wantJSON)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"github.com/gofiber/fiber/v2/log"
+	"gosmart/config"
+	"gosmart/rasterizer"
+)
+
+// DefaultRasterizer é o backend usado para converter em imagem as páginas sem camada de texto.
+var (
+	DefaultRasterizer    rasterizer.Rasterizer
+	DefaultRasterOptions rasterizer.Options
+)
+
+// InitRasterizer registra os backends de rasterização, verifica os executáveis externos
+// e encerra o processo se o backend configurado não puder ser usado.
+func InitRasterizer() {
+	rasterizer.Register(rasterizer.NewMutool(config.GetEnv("RASTERIZER_MUTOOL_PATH")))
+	rasterizer.Register(rasterizer.NewPdftoppm(config.GetEnv("RASTERIZER_PDFTOPPM_PATH")))
+	rasterizer.Register(rasterizer.NewEmbedded())
+
+	reportCapabilities()
+
+	name := config.GetEnv("RASTERIZER")
+	if name == "" {
+		name = "mutool"
+	}
+	selected, err := rasterizer.Get(name)
+	if err != nil {
+		log.Fatalf("RASTERIZER inválido: %v", err)
+	}
+
+	if err := rasterizer.Available(selected); err != nil {
+		fallbackName := config.GetEnv("RASTERIZER_FALLBACK")
+		if fallbackName == "" {
+			log.Fatalf("%v; instale o executável ou configure RASTERIZER/RASTERIZER_FALLBACK", err)
+		}
+		fallback, fallbackErr := rasterizer.Get(fallbackName)
+		if fallbackErr == nil {
+			fallbackErr = rasterizer.Available(fallback)
+		}
+		if fallbackErr != nil {
+			log.Fatalf("%v; RASTERIZER_FALLBACK também indisponível: %v", err, fallbackErr)
+		}
+		log.Warnf("%v; usando %s", err, fallback.Name())
+		selected = fallback
+	}
+
+	pages, err := rasterizer.ParsePages(config.GetEnv("RASTERIZER_PAGES"))
+	if err != nil {
+		log.Warnf("RASTERIZER_PAGES ignorado: %v", err)
+	}
+	options := rasterizer.Options{
+		DPI:       intFromEnv("RASTERIZER_DPI"),
+		Grayscale: config.GetEnv("RASTERIZER_GRAYSCALE") == "true",
+		Pages:     pages,
+	}
+	if err := options.Validate(); err != nil {
+		log.Warnf("opções de rasterização do ambiente ignoradas: %v", err)
+		options = rasterizer.Options{}
+	}
+
+	DefaultRasterizer = selected
+	DefaultRasterOptions = options
+	log.Infof("rasterizador %s configurado", selected.Name())
+}
+
+// reportCapabilities registra no log quais executáveis externos estão disponíveis.
+func reportCapabilities() {
+	tesseract := config.GetEnv("OCR_TESSERACT_PATH")
+	if tesseract == "" {
+		tesseract = "tesseract"
+	}
+
+	var binaries []string
+	for _, name := range []string{"mutool", "pdftoppm"} {
+		if r, err := rasterizer.Get(name); err == nil {
+			binaries = append(binaries, r.Binaries()...)
+		}
+	}
+	binaries = append(binaries, tesseract)
+
+	for _, capability := range rasterizer.CheckBinaries(binaries...) {
+		if capability.Available {
+			log.Infof("executável %s encontrado em %s", capability.Binary, capability.Path)
+		} else {
+			log.Warnf("executável %s não encontrado no PATH", capability.Binary)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/google/uuid"
+	"gosmart/config"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	RateLimitOpenAI     = "openai"
+	RateLimitProcessPDF = "process_pdf"
+	RateLimitPageLLM    = "page_llm"
+
+	rateLimitKeyPrefix  = "gosmart:ratelimit:"
+	minRateLimitBackoff = 50 * time.Millisecond
+)
+
+// rateLimitScript implementa uma janela deslizante com um sorted set por chave: remove as requisições
+// que saíram da janela, conta as restantes e registra a nova se houver espaço. Usa o relógio do Redis,
+// para que réplicas com relógios diferentes compartilhem a mesma janela.
+// Devolve {permitida, restantes, ms até a requisição mais antiga sair da janela}.
+var rateLimitScript = redis.NewScript(`
+local key = KEYS[1]
+local window = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+local count = redis.call('ZCARD', key)
+local allowed = 0
+if count < limit then
+	redis.call('ZADD', key, now, ARGV[3])
+	redis.call('PEXPIRE', key, window)
+	count = count + 1
+	allowed = 1
+end
+
+local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+local reset = window
+if oldest[2] then
+	reset = tonumber(oldest[2]) + window - now
+end
+return {allowed, limit - count, reset}
+`)
+
+// RateLimit permite Limit requisições a cada Window.
+type RateLimit struct {
+	Limit  int
+	Window time.Duration
+}
+
+// Policy devolve a política no formato do cabeçalho RateLimit-Policy (ex.: 60;w=60).
+func (l RateLimit) Policy() string {
+	return fmt.Sprintf("%d;w=%d", l.Limit, int(l.Window.Seconds()))
+}
+
+// RateLimitResult é o resultado de uma verificação. Reset é o tempo até a requisição mais antiga
+// sair da janela, ou seja, até sobrar espaço para uma nova quando Allowed é false.
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	Reset     time.Duration
+}
+
+// RateLimiter aplica limites de requisições por chamador no Redis, válidos entre todas as réplicas.
+type RateLimiter struct {
+	limits map[string]RateLimit
+}
+
+var RateLimits *RateLimiter
+
+// InitRateLimits lê RATE_LIMIT_OPENAI, RATE_LIMIT_PROCESS_PDF e RATE_LIMIT_PAGE_LLM no formato
+// "quantidade/janela" (ex.: 60/1m). Limites vazios não são aplicados.
+func InitRateLimits() {
+	RateLimits = &RateLimiter{limits: map[string]RateLimit{}}
+	for name, key := range map[string]string{
+		RateLimitOpenAI:     "RATE_LIMIT_OPENAI",
+		RateLimitProcessPDF: "RATE_LIMIT_PROCESS_PDF",
+		RateLimitPageLLM:    "RATE_LIMIT_PAGE_LLM",
+	} {
+		value := config.GetEnv(key)
+		if value == "" {
+			continue
+		}
+		limit, err := parseRateLimit(value)
+		if err != nil {
+			log.Warnf("%s inválido (%s), sem limite: %v", key, value, err)
+			continue
+		}
+		RateLimits.limits[name] = limit
+	}
+}
+
+func parseRateLimit(value string) (RateLimit, error) {
+	count, window, ok := strings.Cut(value, "/")
+	if !ok {
+		return RateLimit{}, errors.New("use o formato quantidade/janela")
+	}
+	limit, err := strconv.Atoi(strings.TrimSpace(count))
+	if err != nil || limit <= 0 {
+		return RateLimit{}, fmt.Errorf("quantidade inválida: %s", count)
+	}
+	duration, err := time.ParseDuration(strings.TrimSpace(window))
+	if err != nil || duration < time.Second {
+		return RateLimit{}, fmt.Errorf("janela inválida: %s", window)
+	}
+	return RateLimit{Limit: limit, Window: duration}, nil
+}
+
+// Limit devolve o limite configurado para name.
+func (r *RateLimiter) Limit(name string) (RateLimit, bool) {
+	limit, ok := r.limits[name]
+	return limit, ok
+}
+
+// Allow registra uma requisição do chamador no limite name. Sem limite configurado devolve nil.
+func (r *RateLimiter) Allow(ctx context.Context, name string, caller string) (*RateLimitResult, error) {
+	limit, ok := r.limits[name]
+	if !ok {
+		return nil, nil
+	}
+
+	key := rateLimitKeyPrefix + name + ":" + caller
+	values, err := rateLimitScript.Run(ctx, RedisClient, []string{key}, limit.Window.Milliseconds(), limit.Limit, uuid.New().String()).Int64Slice()
+	if err != nil {
+		return nil, fmt.Errorf("erro ao verificar limite de requisições: %w", err)
+	}
+
+	return &RateLimitResult{
+		Allowed:   values[0] == 1,
+		Limit:     limit.Limit,
+		Remaining: int(values[1]),
+		Reset:     time.Duration(values[2]) * time.Millisecond,
+	}, nil
+}
+
+// Wait bloqueia até o chamador ter espaço no limite name ou o contexto ser cancelado. É usado nas
+// chamadas ao LLM feitas pelas páginas, que estão na fila e podem esperar em vez de falhar.
+// Com o Redis indisponível, a chamada é liberada.
+func (r *RateLimiter) Wait(ctx context.Context, name string, caller string) error {
+	for {
+		if !RedisAvailable() {
+			return nil
+		}
+		result, err := r.Allow(ctx, name, caller)
+		if err != nil {
+			log.Warnf("limite de requisições não verificado: %v", err)
+			return nil
+		}
+		if result == nil || result.Allowed {
+			return nil
+		}
+
+		log.Infof("limite %s de %s atingido, aguardando %s", name, caller, result.Reset)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(max(result.Reset, minRateLimitBackoff)):
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+// useTestRedis conecta ao Redis de REDIS_URL (padrão: banco 15 de localhost:6379) ou pula o teste se ele não responder.
+func useTestRedis(t *testing.T) {
+	t.Helper()
+	if os.Getenv("REDIS_URL") == "" {
+		t.Setenv("REDIS_URL", "redis://localhost:6379/15")
+	}
+	t.Setenv("REDIS_CONNECT_RETRIES", "0")
+	t.Setenv("REDIS_HEALTH_INTERVAL", "0")
+	InitRedis()
+	if !RedisAvailable() {
+		t.Skip("Redis indisponível")
+	}
+}
+
+// useAPIKeys substitui as chaves de API_KEYS durante o teste.
+func useAPIKeys(t *testing.T, keys map[string]string) {
+	t.Helper()
+	previous := apiKeyNames
+	apiKeyNames = keys
+	t.Cleanup(func() { apiKeyNames = previous })
+}
+
+func TestCallerIDSharesUnknownKeys(t *testing.T) {
+	useAPIKeys(t, map[string]string{"chave-erp": "erp"})
+
+	tests := []struct {
+		name   string
+		apiKey string
+		want   string
+	}{
+		{name: "chave configurada", apiKey: "chave-erp", want: "erp"},
+		{name: "sem chave", apiKey: "", want: AnonymousCaller},
+		{name: "chave desconhecida", apiKey: "aleatoria-1", want: AnonymousCaller},
+		{name: "outra chave desconhecida", apiKey: "aleatoria-2", want: AnonymousCaller},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CallerID(tt.apiKey); got != tt.want {
+				t.Errorf("CallerID(%q) = %q, esperado %q", tt.apiKey, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimitWindowSurvivesKeyChange(t *testing.T) {
+	useTestRedis(t)
+	useAPIKeys(t, map[string]string{})
+
+	name := "teste_" + t.Name()
+	limiter := &RateLimiter{limits: map[string]RateLimit{name: {Limit: 2, Window: time.Minute}}}
+	ctx := context.Background()
+	RedisClient.Del(ctx, rateLimitKeyPrefix+name+":"+AnonymousCaller)
+	t.Cleanup(func() { RedisClient.Del(ctx, rateLimitKeyPrefix+name+":"+AnonymousCaller) })
+
+	for i, apiKey := range []string{"", "aleatoria-1", "aleatoria-2", "aleatoria-3"} {
+		result, err := limiter.Allow(ctx, name, CallerID(apiKey))
+		if err != nil {
+			t.Fatalf("Allow: %v", err)
+		}
+		if want := i < 2; result.Allowed != want {
+			t.Errorf("requisição %d com chave %q: permitida = %v, esperado %v", i+1, apiKey, result.Allowed, want)
+		}
+	}
+}
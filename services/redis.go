@@ -2,20 +2,184 @@ package services
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
 	"github.com/go-redis/redis/v8"
+	"github.com/gofiber/fiber/v2/log"
 	"gosmart/config"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 )
 
-var RedisClient *redis.Client
+const (
+	defaultRedisAddr           = "localhost:6379"
+	defaultRedisConnectRetries = 5
+	defaultRedisHealthInterval = 10 * time.Second
+	redisRetryBaseDelay        = 500 * time.Millisecond
+	redisRetryMaxDelay         = 5 * time.Second
+)
+
+// RedisClient atende Redis simples, Sentinel (REDIS_MASTER_NAME) e Cluster (vários endereços ou REDIS_CLUSTER=true).
+// No Cluster, transações com chaves em slots diferentes são executadas por slot, sem atomicidade entre eles.
+var RedisClient redis.UniversalClient
+
+// redisUp reflete o último ping. Recursos não essenciais (auditoria, cache de resultados) consultam
+// RedisAvailable para serem ignorados enquanto o Redis está fora, em vez de esperar o timeout a cada uso.
+var redisUp atomic.Bool
 
+var errRedisUnavailable = errors.New("Redis indisponível")
+
+// InitRedis cria o cliente a partir de REDIS_URL e das variáveis REDIS_*, testa a conexão com novas
+// tentativas e passa a monitorá-la. Se o Redis não responder, a aplicação sobe em modo degradado.
 func InitRedis() {
-	RedisClient = redis.NewClient(&redis.Options{
-		Addr: config.GetEnv("REDIS_URL"),
-		DB:   0,
-	})
+	options, err := redisOptionsFromEnv()
+	if err != nil {
+		log.Fatalf("configuração do Redis inválida: %v", err)
+	}
+
+	if options.MasterName == "" && (len(options.Addrs) > 1 || config.GetEnv("REDIS_CLUSTER") == "true") {
+		RedisClient = redis.NewClusterClient(options.Cluster())
+	} else {
+		RedisClient = redis.NewUniversalClient(options)
+	}
+
+	if err := pingRedis(context.Background(), limitFromEnv("REDIS_CONNECT_RETRIES", defaultRedisConnectRetries)); err != nil {
+		log.Errorf("Redis indisponível em %s, iniciando em modo degradado: %v", strings.Join(options.Addrs, ","), err)
+	} else {
+		log.Infof("conectado ao Redis em %s", strings.Join(options.Addrs, ","))
+	}
+
+	go watchRedis(context.Background(), durationFromEnv("REDIS_HEALTH_INTERVAL", defaultRedisHealthInterval))
+}
+
+// redisOptionsFromEnv monta as opções a partir de REDIS_URL (redis:// ou rediss://, ou apenas host:porta)
+// e das variáveis discretas, que têm precedência sobre a URL.
+func redisOptionsFromEnv() (*redis.UniversalOptions, error) {
+	options := &redis.UniversalOptions{}
+
+	if value := config.GetEnv("REDIS_URL"); strings.HasPrefix(value, "redis://") || strings.HasPrefix(value, "rediss://") {
+		parsed, err := redis.ParseURL(value)
+		if err != nil {
+			return nil, fmt.Errorf("REDIS_URL: %w", err)
+		}
+		options.Addrs = []string{parsed.Addr}
+		options.Username = parsed.Username
+		options.Password = parsed.Password
+		options.DB = parsed.DB
+		options.TLSConfig = parsed.TLSConfig
+	} else if value != "" {
+		options.Addrs = splitList(value)
+	}
+
+	if addrs := splitList(config.GetEnv("REDIS_ADDR")); len(addrs) > 0 {
+		options.Addrs = addrs
+	}
+	if len(options.Addrs) == 0 {
+		options.Addrs = []string{defaultRedisAddr}
+	}
+	if value := config.GetEnv("REDIS_USERNAME"); value != "" {
+		options.Username = value
+	}
+	if value := config.GetEnv("REDIS_PASSWORD"); value != "" {
+		options.Password = value
+	}
+	if value := config.GetEnv("REDIS_DB"); value != "" {
+		db, err := strconv.Atoi(value)
+		if err != nil || db < 0 {
+			return nil, fmt.Errorf("REDIS_DB inválido: %s", value)
+		}
+		options.DB = db
+	}
+
+	if value := config.GetEnv("REDIS_TLS"); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("REDIS_TLS inválido: %s", value)
+		}
+		if !enabled {
+			options.TLSConfig = nil
+		} else if options.TLSConfig == nil {
+			options.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+	}
+	if options.TLSConfig != nil {
+		if serverName := config.GetEnv("REDIS_TLS_SERVER_NAME"); serverName != "" {
+			options.TLSConfig.ServerName = serverName
+		}
+		options.TLSConfig.InsecureSkipVerify = config.GetEnv("REDIS_TLS_INSECURE_SKIP_VERIFY") == "true"
+	}
+
+	options.MasterName = config.GetEnv("REDIS_MASTER_NAME")
+	options.SentinelPassword = config.GetEnv("REDIS_SENTINEL_PASSWORD")
+	if options.MasterName == "" && options.DB != 0 && (len(options.Addrs) > 1 || config.GetEnv("REDIS_CLUSTER") == "true") {
+		return nil, errors.New("REDIS_DB não é suportado pelo Redis Cluster")
+	}
+
+	options.PoolSize = limitFromEnv("REDIS_POOL_SIZE", 0)
+	options.MinIdleConns = limitFromEnv("REDIS_MIN_IDLE_CONNS", 0)
+	options.MaxRetries = limitFromEnv("REDIS_MAX_RETRIES", 0)
+	options.DialTimeout = durationFromEnv("REDIS_DIAL_TIMEOUT", 0)
+	options.ReadTimeout = durationFromEnv("REDIS_READ_TIMEOUT", 0)
+	options.WriteTimeout = durationFromEnv("REDIS_WRITE_TIMEOUT", 0)
+	options.PoolTimeout = durationFromEnv("REDIS_POOL_TIMEOUT", 0)
+
+	return options, nil
+}
+
+// pingRedis testa a conexão até retries vezes, com espera crescente entre as tentativas.
+func pingRedis(ctx context.Context, retries int) error {
+	delay := redisRetryBaseDelay
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = RedisClient.Ping(ctx).Err(); err == nil {
+			redisUp.Store(true)
+			return nil
+		}
+		if attempt >= retries {
+			redisUp.Store(false)
+			return err
+		}
+
+		log.Warnf("Redis não respondeu (tentativa %d de %d): %v", attempt+1, retries+1, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, redisRetryMaxDelay)
+	}
+}
+
+// watchRedis atualiza RedisAvailable periodicamente e registra as mudanças de estado.
+func watchRedis(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := RedisClient.Ping(ctx).Err()
+		if up := err == nil; redisUp.Swap(up) != up {
+			if up {
+				log.Info("conexão com o Redis restabelecida")
+			} else {
+				log.Errorf("Redis indisponível, auditoria e cache de resultados suspensos: %v", err)
+			}
+		}
+	}
 }
 
-func LogToRedis(key string, value string) error {
-	ctx := context.Background()
-	return RedisClient.Set(ctx, key, value, 0).Err()
+// RedisAvailable indica se o último ping ao Redis teve sucesso.
+func RedisAvailable() bool {
+	return redisUp.Load()
 }
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"github.com/gofiber/fiber/v2/log"
+	"gosmart/entities"
+	"gosmart/ocr"
+	"gosmart/schema"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// PipelineVersion entra em todas as chaves do cache de resultados. Deve ser incrementada quando
+	// prompts ou o pós-processamento mudarem, para que resultados antigos deixem de ser reaproveitados.
+	PipelineVersion = "1"
+
+	resultCacheKeyPrefix   = "gosmart:cache:"
+	defaultResultCacheTTL  = 7 * 24 * time.Hour
+	extractionInputText    = "text"
+	extractionInputImage   = "image"
+	resultCacheSchemaEmpty = "none"
+)
+
+// ResultCache reaproveita o texto do OCR e os resultados de extração de páginas já processadas.
+// As chaves são o hash do conteúdo (imagem da página ou texto enviado ao LLM), a versão do pipeline e as
+// opções que alteram o resultado, então o mesmo PDF enviado de novo não repete OCR nem chamadas ao LLM.
+type ResultCache struct {
+	ttl time.Duration
+}
+
+var Cache *ResultCache
+
+// InitResultCache lê CACHE_TTL; zero desativa o cache.
+func InitResultCache() {
+	Cache = &ResultCache{ttl: durationFromEnv("CACHE_TTL", defaultResultCacheTTL)}
+}
+
+// Enabled indica se o cache está configurado e o Redis está no ar.
+func (c *ResultCache) Enabled() bool {
+	return c != nil && c.ttl > 0 && RedisAvailable()
+}
+
+// OCRText devolve o texto reconhecido anteriormente para a mesma imagem, backend e opções.
+func (c *ResultCache) OCRText(ctx context.Context, key string) (string, bool) {
+	text, err := RedisClient.Get(ctx, key).Result()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			log.Warnf("erro ao ler cache de OCR: %v", err)
+		}
+		return "", false
+	}
+	return text, true
+}
+
+func (c *ResultCache) StoreOCRText(ctx context.Context, key string, text string) {
+	if err := RedisClient.Set(ctx, key, text, c.ttl).Err(); err != nil {
+		log.Warnf("erro ao gravar cache de OCR: %v", err)
+	}
+}
+
+// Extraction devolve a extração gravada para a mesma chave. O consumo de tokens é zerado: o acerto não custa nada.
+func (c *ResultCache) Extraction(ctx context.Context, key string) (*ExtractionResult, bool) {
+	data, err := RedisClient.Get(ctx, key).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			log.Warnf("erro ao ler cache de extração: %v", err)
+		}
+		return nil, false
+	}
+
+	var extraction ExtractionResult
+	if err := json.Unmarshal(data, &extraction); err != nil {
+		log.Warnf("entrada inválida no cache de extração %s: %v", key, err)
+		return nil, false
+	}
+	extraction.Usage = entities.TokenUsage{}
+	extraction.Attempts = 0
+	return &extraction, true
+}
+
+func (c *ResultCache) StoreExtraction(ctx context.Context, key string, extraction *ExtractionResult) {
+	data, err := json.Marshal(extraction)
+	if err != nil {
+		log.Warnf("erro ao serializar extração para o cache: %v", err)
+		return
+	}
+	if err := RedisClient.Set(ctx, key, data, c.ttl).Err(); err != nil {
+		log.Warnf("erro ao gravar cache de extração: %v", err)
+	}
+}
+
+// ocrCacheKey identifica o texto do OCR de uma imagem com um backend e opções.
+func ocrCacheKey(imageHash string, engine string, options ocr.Options) string {
+	return fmt.Sprintf("%socr:%s:%s:%s:%s", resultCacheKeyPrefix, PipelineVersion, engine, fingerprint(options), imageHash)
+}
+
+// extractionCacheKey identifica a extração de um conteúdo (texto ou imagem) com um esquema, pelo modelo
+// que a política escolhe hoje para a operação: trocar o modelo não reaproveita extrações do anterior.
+// Devolve "" quando o modelo não pode ser escolhido, e a extração não passa pelo cache.
+func extractionCacheKey(ctx context.Context, operation Operation, input string, contentHash string, extractionSchema *schema.Schema) string {
+	if !Cache.Enabled() {
+		return ""
+	}
+	model, err := SelectModel(ctx, operation)
+	if err != nil {
+		return ""
+	}
+
+	schemaKey := resultCacheSchemaEmpty
+	if extractionSchema != nil {
+		schemaKey = SchemaTransport + "-" + fingerprint(extractionSchema)
+	}
+	return fmt.Sprintf("%sextraction:%s:%s:%s:%s:%s", resultCacheKeyPrefix, PipelineVersion, model, input, schemaKey, contentHash)
+}
+
+// contentHash devolve o SHA-256 em hexadecimal.
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// fingerprint resume um valor serializável em um hash curto, para compor chaves.
+func fingerprint(value interface{}) string {
+	data, _ := json.Marshal(value)
+	return contentHash(data)[:16]
+}
+
+// FileHash devolve o SHA-256 do arquivo.
+func FileHash(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// cachedExtraction consulta o cache antes de executar fn e grava o resultado novo. Só extrações completas
+// e sem violações de esquema são gravadas, para que um resultado ruim não se repita a cada envio.
+func cachedExtraction(ctx context.Context, key string, options ProcessingOptions, status *entities.PageCache, fn func() (*ExtractionResult, error)) (*ExtractionResult, error) {
+	if !Cache.Enabled() || key == "" {
+		return fn()
+	}
+
+	if options.NoCache {
+		status.Extraction = entities.CacheBypass
+	} else if extraction, ok := Cache.Extraction(ctx, key); ok {
+		status.Extraction = entities.CacheHit
+		return extraction, nil
+	} else {
+		status.Extraction = entities.CacheMiss
+	}
+
+	extraction, err := fn()
+	if err == nil && !extraction.Truncated && len(extraction.SchemaErrors) == 0 {
+		Cache.StoreExtraction(ctx, key, extraction)
+	}
+	return extraction, err
+}
+
+// cachedOCR consulta o cache de OCR antes de executar o backend e grava o texto reconhecido.
+func cachedOCR(ctx context.Context, key string, options ProcessingOptions, status *entities.PageCache, fn func() (string, error)) (string, error) {
+	if !Cache.Enabled() {
+		return fn()
+	}
+
+	if options.NoCache {
+		status.OCR = entities.CacheBypass
+	} else if text, ok := Cache.OCRText(ctx, key); ok {
+		status.OCR = entities.CacheHit
+		return text, nil
+	} else {
+		status.OCR = entities.CacheMiss
+	}
+
+	text, err := fn()
+	if err == nil && strings.TrimSpace(text) != "" {
+		Cache.StoreOCRText(ctx, key, text)
+	}
+	return text, err
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gofiber/fiber/v2/log"
+	"gosmart/config"
+	"gosmart/entities"
+	"gosmart/jsonrepair"
+	"gosmart/schema"
+	"strings"
+)
+
+// Formas de enviar o esquema ao modelo: response_format json_schema (modelos gpt-4o de 2024-08-06 em diante)
+// ou uma chamada de função obrigatória, aceita também pelos modelos anteriores.
+const (
+	SchemaTransportJSONSchema = "json_schema"
+	SchemaTransportTools      = "tools"
+)
+
+// ProductsSchema é o esquema padrão para listas de produtos de fornecedores.
+var ProductsSchema = &schema.Schema{
+	Name:        "produtos",
+	Description: "Lista de produtos de um fornecedor",
+	Fields: []schema.Field{
+		{
+			Name:     "produtos",
+			Type:     schema.TypeArray,
+			Required: true,
+			Items: &schema.Field{
+				Type: schema.TypeObject,
+				Fields: []schema.Field{
+					{Name: "codigo", Type: schema.TypeString, Required: true, Description: "Código do produto, sem pontos ou traços"},
+					{Name: "descricao", Type: schema.TypeString, Required: true},
+					{Name: "unidade", Type: schema.TypeString, Description: "Unidade de medida (UN, CX, KG...)"},
+					{Name: "quantidade", Type: schema.TypeNumber},
+					{Name: "preco_unitario", Type: schema.TypeNumber},
+					{Name: "ncm", Type: schema.TypeString},
+					{Name: "ean", Type: schema.TypeString},
+				},
+			},
+		},
+	},
+}
+
+// DefaultSchema é o esquema aplicado quando a requisição não escolhe nenhum; nil mantém a extração livre.
+var (
+	DefaultSchema   *schema.Schema
+	SchemaTransport = SchemaTransportTools
+)
+
+// InitSchemas registra o esquema padrão de produtos e os esquemas do diretório EXTRACTION_SCHEMAS_DIR.
+func InitSchemas() {
+	if err := schema.Register(ProductsSchema); err != nil {
+		log.Error("erro ao registrar esquema de produtos: ", err)
+	}
+
+	if dir := config.GetEnv("EXTRACTION_SCHEMAS_DIR"); dir != "" {
+		count, err := schema.LoadDir(dir)
+		if err != nil {
+			log.Error("erro ao carregar esquemas de extração: ", err)
+		} else {
+			log.Infof("%d esquema(s) de extração carregados de %s", count, dir)
+		}
+	}
+
+	if name := config.GetEnv("EXTRACTION_SCHEMA"); name != "" {
+		s, err := schema.Get(name)
+		if err != nil {
+			log.Warnf("EXTRACTION_SCHEMA inválido (%s), usando extração livre", name)
+		} else {
+			DefaultSchema = s
+		}
+	}
+
+	switch transport := config.GetEnv("EXTRACTION_SCHEMA_MODE"); transport {
+	case "":
+	case SchemaTransportJSONSchema, SchemaTransportTools:
+		SchemaTransport = transport
+	default:
+		log.Warnf("EXTRACTION_SCHEMA_MODE inválido (%s), usando %s", transport, SchemaTransport)
+	}
+}
+
+// applySchema restringe a resposta ao esquema, pelo response_format ou por uma chamada de função obrigatória.
+func applySchema(request *entities.ChatCompletionRequest, s *schema.Schema) {
+	if SchemaTransport == SchemaTransportJSONSchema {
+		request.ResponseFormat = &entities.ResponseFormat{
+			Type: "json_schema",
+			JSONSchema: &entities.JSONSchemaFormat{
+				Name:        s.Name,
+				Description: s.Description,
+				Schema:      s.JSONSchema(true),
+				Strict:      true,
+			},
+		}
+		return
+	}
+
+	request.Tools = []entities.Tool{{
+		Type: "function",
+		Function: entities.ToolFunction{
+			Name:        s.Name,
+			Description: s.Description,
+			Parameters:  s.JSONSchema(false),
+		},
+	}}
+	request.ToolChoice = &entities.ToolChoice{Type: "function"}
+	request.ToolChoice.Function.Name = s.Name
+}
+
+// schemaInstructions complementa o prompt com os campos esperados, em vez de deixar o modelo escolher as chaves.
+func schemaInstructions(s *schema.Schema) string {
+	definition, _ := json.Marshal(s.JSONSchema(false))
+	return fmt.Sprintf(`
+    Responda usando exatamente os campos do esquema "%s", sem inventar outras chaves.
+    Campos opcionais sem valor no documento devem ser omitidos.
+    ESQUEMA:
+    %s
+`, s.Name, definition)
+}
+
+// completeSchema envia a requisição restrita ao esquema e valida a resposta. Se ela não estiver conforme,
+// o modelo recebe os erros de validação e uma única nova chance; os erros que restarem são devolvidos.
+func completeSchema(ctx context.Context, request entities.ChatCompletionRequest, s *schema.Schema) (map[string]interface{}, []string, callStats, error) {
+	applySchema(&request, s)
+
+	content, stats, err := completeText(ctx, request)
+	if err != nil {
+		return nil, nil, stats, err
+	}
+
+	data, repairs, violations := decodeAndValidate(content, stats.FinishReason, s)
+	stats.Repairs = repairs
+	if len(violations) == 0 {
+		return data, nil, stats, nil
+	}
+
+	log.Warnf("resposta fora do esquema %s, solicitando correção: %s", s.Name, strings.Join(violations, "; "))
+	request.Messages = append(request.Messages,
+		entities.ChatCompletionMessage{Role: "assistant", Content: content},
+		entities.ChatCompletionMessage{Role: "user", Content: "A resposta não está conforme o esquema. Corrija os seguintes erros e responda novamente com o JSON completo:\n- " + strings.Join(violations, "\n- ")},
+	)
+
+	retryContent, retryStats, err := completeText(ctx, request)
+	stats.Attempts += retryStats.Attempts
+	stats.Reprompted = true
+	stats.FinishReason = retryStats.FinishReason
+	stats.Usage.Add(retryStats.Usage)
+	if err != nil {
+		return nil, nil, stats, err
+	}
+
+	retryData, retryRepairs, retryViolations := decodeAndValidate(retryContent, retryStats.FinishReason, s)
+	if retryData == nil {
+		if data == nil {
+			return nil, nil, stats, fmt.Errorf("erro ao parsear JSON retornado: %s", strings.Join(retryViolations, "; "))
+		}
+		// A primeira resposta era JSON, ainda que fora do esquema; é melhor que nenhuma
+		return data, violations, stats, nil
+	}
+	stats.Repairs = retryRepairs
+	return retryData, retryViolations, stats, nil
+}
+
+// decodeAndValidate extrai o JSON da resposta, aplicando os reparos necessários, e o valida contra o esquema.
+func decodeAndValidate(content string, finishReason string, s *schema.Schema) (map[string]interface{}, []string, []string) {
+	var data map[string]interface{}
+	repairs, err := jsonrepair.Unmarshal(content, finishReason == finishReasonLength, &data)
+	if err != nil {
+		return nil, nil, []string{"a resposta não é um objeto JSON válido: " + err.Error()}
+	}
+	return data, repairs, s.Validate(data)
+}
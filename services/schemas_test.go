@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"gosmart/entities"
+	"gosmart/jsonrepair"
+	"reflect"
+	"testing"
+)
+
+func TestApplySchema(t *testing.T) {
+	tests := []struct {
+		transport string
+		strict    bool
+	}{
+		{transport: SchemaTransportTools},
+		{transport: SchemaTransportJSONSchema, strict: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.transport, func(t *testing.T) {
+			previous := SchemaTransport
+			SchemaTransport = tt.transport
+			t.Cleanup(func() { SchemaTransport = previous })
+
+			var request entities.ChatCompletionRequest
+			applySchema(&request, ProductsSchema)
+
+			want := ProductsSchema.JSONSchema(tt.strict)
+			if tt.strict {
+				if request.ResponseFormat == nil || request.ResponseFormat.JSONSchema == nil || !request.ResponseFormat.JSONSchema.Strict {
+					t.Fatalf("response_format = %+v, esperado json_schema strict", request.ResponseFormat)
+				}
+				if len(request.Tools) > 0 {
+					t.Error("tools enviadas junto com response_format")
+				}
+				if !reflect.DeepEqual(request.ResponseFormat.JSONSchema.Schema, want) {
+					t.Error("response_format com esquema diferente de JSONSchema(true)")
+				}
+				return
+			}
+
+			if request.ResponseFormat != nil {
+				t.Error("response_format enviado no modo tools")
+			}
+			if len(request.Tools) != 1 || request.ToolChoice == nil || request.ToolChoice.Function.Name != ProductsSchema.Name {
+				t.Fatalf("tools = %+v, tool_choice = %+v, esperado a função %s obrigatória", request.Tools, request.ToolChoice, ProductsSchema.Name)
+			}
+			if !reflect.DeepEqual(request.Tools[0].Function.Parameters, want) {
+				t.Error("parâmetros da função diferentes de JSONSchema(false)")
+			}
+		})
+	}
+}
+
+func TestDecodeAndValidate(t *testing.T) {
+	tests := []struct {
+		name         string
+		content      string
+		finishReason string
+		repairs      []string
+		violations   []string
+		noData       bool
+	}{
+		{
+			name:    "conforme",
+			content: `{"produtos": [{"codigo": "1", "descricao": "Parafuso", "preco_unitario": 1.5}]}`,
+		},
+		{
+			name:       "fora do esquema",
+			content:    `{"produtos": [{"codigo": 1, "preco_unitario": "1,50"}]}`,
+			violations: []string{"$.produtos[0].codigo: esperado string, recebido integer", "$.produtos[0].descricao: campo obrigatório ausente", "$.produtos[0].preco_unitario: esperado number, recebido string"},
+		},
+		{
+			name:         "JSON cortado e reparado",
+			content:      `{"produtos": [{"codigo": "1", "descricao": "Parafuso"}, {"codigo": "2", "desc`,
+			finishReason: finishReasonLength,
+			repairs:      []string{jsonrepair.RepairTruncated},
+		},
+		{
+			name:       "sem JSON",
+			content:    "Não encontrei produtos.",
+			violations: []string{"a resposta não é um objeto JSON válido: nenhum JSON encontrado na resposta"},
+			noData:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, repairs, violations := decodeAndValidate(tt.content, tt.finishReason, ProductsSchema)
+			if (data == nil) != tt.noData {
+				t.Errorf("dados = %v", data)
+			}
+			if !reflect.DeepEqual(repairs, tt.repairs) {
+				t.Errorf("reparos = %q, esperado %q", repairs, tt.repairs)
+			}
+			if !reflect.DeepEqual(violations, tt.violations) {
+				t.Errorf("violações = %q, esperado %q", violations, tt.violations)
+			}
+		})
+	}
+}
+
+func TestCompleteSchemaRepromptsOnce(t *testing.T) {
+	api := useFakeOpenAI(t, `{"produtos": [{"codigo": "1"}]}`)
+
+	request := entities.ChatCompletionRequest{
+		Model:    "gpt-4o",
+		Messages: []entities.ChatCompletionMessage{{Role: "user", Content: "extraia"}},
+	}
+	data, violations, stats, err := completeSchema(context.Background(), request, ProductsSchema)
+	if err != nil {
+		t.Fatalf("completeSchema: %v", err)
+	}
+	if data == nil || !stats.Reprompted {
+		t.Errorf("dados = %v, reprompt = %v, esperado dados e nova tentativa", data, stats.Reprompted)
+	}
+	if want := []string{"$.produtos[0].descricao: campo obrigatório ausente"}; !reflect.DeepEqual(violations, want) {
+		t.Errorf("violações = %q, esperado %q", violations, want)
+	}
+
+	var messages []entities.ChatCompletionMessage
+	if err := json.Unmarshal(api.lastRequest(t)["messages"], &messages); err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 3 || messages[2].Role != "user" {
+		t.Errorf("%d mensagens na nova tentativa, esperado a original, a resposta e os erros", len(messages))
+	}
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v2/log"
+	"gosmart/entities"
+	"gosmart/tokenizer"
+	"math"
+)
+
+const (
+	// minCompletionTokens é o espaço mínimo reservado para a resposta dentro da janela de contexto.
+	minCompletionTokens = 1024
+	// promptTokenMargin é a folga somada à estimativa do tokenizer, que é heurística e pode ficar abaixo da real.
+	promptTokenMargin = 0.1
+)
+
+var ErrPromptTooLarge = errors.New("prompt excede o orçamento de tokens")
+
+// PromptBudgetError informa a estimativa de tokens do prompt recusado e o limite aplicado.
+type PromptBudgetError struct {
+	Model     string
+	Estimated int
+	Limit     int
+}
+
+func (e *PromptBudgetError) Error() string {
+	return fmt.Sprintf("%s: %d tokens estimados para %s, limite de %d", ErrPromptTooLarge, e.Estimated, e.Model, e.Limit)
+}
+
+func (e *PromptBudgetError) Is(target error) bool {
+	return target == ErrPromptTooLarge
+}
+
+// fitBudget estima os tokens do prompt antes do envio, com a folga promptTokenMargin, e recusa prompts que não cabem na janela de contexto
+// do modelo (reservando minCompletionTokens) ou que passam de OPENAI_MAX_PROMPT_TOKENS. Quando o prompt cabe
+// mas max_tokens estouraria a janela, max_tokens é reduzido. Devolve a estimativa.
+func fitBudget(request *entities.ChatCompletionRequest) (int, error) {
+	estimated := int(math.Ceil(float64(tokenizer.CountRequest(*request)) * (1 + promptTokenMargin)))
+	window := tokenizer.ContextWindow(request.Model)
+
+	limit := window - minCompletionTokens
+	if configured := intFromEnv("OPENAI_MAX_PROMPT_TOKENS"); configured > 0 && configured < limit {
+		limit = configured
+	}
+	if estimated > limit {
+		return estimated, &PromptBudgetError{Model: request.Model, Estimated: estimated, Limit: limit}
+	}
+
+	if request.MaxTokens > 0 && estimated+request.MaxTokens > window {
+		log.Warnf("max_tokens reduzido de %d para %d para caber na janela de %d tokens do %s", request.MaxTokens, window-estimated, window, request.Model)
+		request.MaxTokens = window - estimated
+	}
+	return estimated, nil
+}
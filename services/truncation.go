@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gofiber/fiber/v2/log"
+	"gosmart/config"
+	"gosmart/entities"
+	"gosmart/schema"
+	"strconv"
+	"strings"
+)
+
+// finishReasonLength é o finish_reason devolvido quando a resposta atinge max_tokens.
+const finishReasonLength = "length"
+
+const (
+	defaultMaxChunkDepth    = 3
+	defaultMaxContinuations = 2
+
+	// maxBudgetSplitDepth limita as divisões feitas porque o texto não cabe no orçamento do prompt (até 1024 partes).
+	maxBudgetSplitDepth = 10
+)
+
+// extractTextInChunks extrai os dados do texto e, se a resposta for cortada, divide o texto ao meio
+// em uma quebra de linha e processa cada metade separadamente, juntando os resultados. As divisões
+// param em EXTRACTION_MAX_CHUNK_DEPTH níveis; depois disso, a resposta é completada por continuação.
+func extractTextInChunks(ctx context.Context, model string, text string, header string, extractionSchema *schema.Schema, depth int) (*ExtractionResult, error) {
+	request := textExtractionRequest(model, text, header, extractionSchema)
+	result, err := extract(ctx, model, request, extractionSchema)
+	if errors.Is(err, ErrPromptTooLarge) {
+		// O texto não cabe no orçamento de um prompt: divide antes de gastar com a chamada
+		if _, _, ok := splitRows(text); !ok || depth >= maxBudgetSplitDepth {
+			return nil, err
+		}
+		log.Warnf("texto excede o orçamento de tokens, dividindo em duas partes (nível %d): %v", depth+1, err)
+		return extractHalves(ctx, model, text, header, extractionSchema, depth)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !result.Truncated {
+		return result, nil
+	}
+
+	if _, _, ok := splitRows(text); !ok || depth >= limitFromEnv("EXTRACTION_MAX_CHUNK_DEPTH", defaultMaxChunkDepth) {
+		return continueExtraction(ctx, model, request, extractionSchema, result)
+	}
+
+	log.Warnf("resposta cortada pelo limite de tokens, dividindo o texto em duas partes (nível %d)", depth+1)
+	merged, err := extractHalves(ctx, model, text, header, extractionSchema, depth)
+	if err != nil {
+		return nil, err
+	}
+
+	// A tentativa inteira foi descartada, mas seu consumo continua contando
+	merged.Attempts += result.Attempts
+	merged.Usage.Add(result.Usage)
+	return merged, nil
+}
+
+// extractHalves processa as duas metades do texto e junta os resultados. A segunda metade recebe
+// a primeira linha do texto como contexto das colunas.
+func extractHalves(ctx context.Context, model string, text string, header string, extractionSchema *schema.Schema, depth int) (*ExtractionResult, error) {
+	first, second, _ := splitRows(text)
+	if header == "" {
+		header = firstLine(text)
+	}
+
+	left, err := extractTextInChunks(ctx, model, first, "", extractionSchema, depth+1)
+	if err != nil {
+		return nil, err
+	}
+	right, err := extractTextInChunks(ctx, model, second, header, extractionSchema, depth+1)
+	if err != nil {
+		return nil, err
+	}
+	return mergeExtractions(left, right), nil
+}
+
+// continueExtraction pede ao modelo os itens que faltaram enquanto a resposta vier cortada,
+// até EXTRACTION_MAX_CONTINUATIONS vezes. Cada continuação é um JSON novo com os itens restantes.
+func continueExtraction(ctx context.Context, model string, request entities.ChatCompletionRequest, extractionSchema *schema.Schema, result *ExtractionResult) (*ExtractionResult, error) {
+	limit := limitFromEnv("EXTRACTION_MAX_CONTINUATIONS", defaultMaxContinuations)
+	for result.Truncated && result.Continuations < limit {
+		partial, err := json.Marshal(result.Data)
+		if err != nil {
+			return result, nil
+		}
+
+		log.Warnf("resposta cortada pelo limite de tokens, solicitando continuação (%d/%d)", result.Continuations+1, limit)
+		next := request
+		next.Messages = append(append([]entities.ChatCompletionMessage(nil), request.Messages...),
+			entities.ChatCompletionMessage{Role: "assistant", Content: string(partial)},
+			entities.ChatCompletionMessage{Role: "user", Content: "A resposta anterior foi interrompida pelo limite de tamanho. Continue a extração a partir do item seguinte ao último item listado acima e responda com um novo JSON, no mesmo formato, contendo SOMENTE os itens restantes."},
+		)
+
+		continuation, err := extract(ctx, model, next, extractionSchema)
+		if err != nil {
+			// Mantém o que já foi extraído; o resultado segue marcado como incompleto
+			log.Error("erro ao solicitar continuação: ", err)
+			return result, nil
+		}
+
+		chunks, continuations := result.Chunks, result.Continuations+1
+		result = mergeExtractions(result, continuation)
+		result.Truncated = continuation.Truncated
+		result.Chunks = chunks
+		result.Continuations = continuations
+	}
+	return result, nil
+}
+
+// mergeExtractions junta dois resultados parciais da mesma página.
+func mergeExtractions(a, b *ExtractionResult) *ExtractionResult {
+	merged := *a
+	merged.Data = mergeData(a.Data, b.Data)
+	merged.Attempts += b.Attempts
+	merged.Usage.Add(b.Usage)
+	merged.SchemaErrors = append(append([]string(nil), a.SchemaErrors...), b.SchemaErrors...)
+	merged.Reprompted = a.Reprompted || b.Reprompted
+	merged.Truncated = a.Truncated || b.Truncated
+	merged.Chunks = max(a.Chunks, 1) + max(b.Chunks, 1)
+	merged.Continuations += b.Continuations
+
+	merged.JSONRepairs = append([]string(nil), a.JSONRepairs...)
+	for _, repair := range b.JSONRepairs {
+		if !containsString(merged.JSONRepairs, repair) {
+			merged.JSONRepairs = append(merged.JSONRepairs, repair)
+		}
+	}
+	return &merged
+}
+
+// mergeData concatena as listas presentes nos dois objetos e combina objetos aninhados;
+// para os demais valores, prevalece o da primeira parte.
+func mergeData(a, b map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(a)+len(b))
+	for key, value := range a {
+		merged[key] = value
+	}
+	for key, value := range b {
+		existing, ok := merged[key]
+		if !ok || existing == nil {
+			merged[key] = value
+			continue
+		}
+		switch current := existing.(type) {
+		case []interface{}:
+			if next, ok := value.([]interface{}); ok {
+				merged[key] = append(append([]interface{}(nil), current...), next...)
+			}
+		case map[string]interface{}:
+			if next, ok := value.(map[string]interface{}); ok {
+				merged[key] = mergeData(current, next)
+			}
+		}
+	}
+	return merged
+}
+
+// splitRows divide o texto ao meio na quebra de linha mais próxima, para não cortar uma linha da tabela.
+func splitRows(text string) (string, string, bool) {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	if len(lines) < 2 {
+		return "", "", false
+	}
+	middle := len(lines) / 2
+	return strings.Join(lines[:middle], "\n"), strings.Join(lines[middle:], "\n"), true
+}
+
+func firstLine(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+func limitFromEnv(key string, fallback int) int {
+	value := config.GetEnv(key)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 0 {
+		log.Warnf("%s inválido (%s), usando %d", key, value, fallback)
+		return fallback
+	}
+	return parsed
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"github.com/gofiber/fiber/v2/log"
+	"gosmart/config"
+	"gosmart/entities"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	usageKeyPrefix        = "gosmart:usage:"
+	usageDateLayout       = "2006-01-02"
+	usageMonthLayout      = "2006-01"
+	defaultUsageRetention = 400 * 24 * time.Hour
+	maxUsageReportDays    = 366
+	microsPerUSD          = 1_000_000
+)
+
+// UsageRangeError informa por que o intervalo pedido ao relatório de consumo foi recusado.
+type UsageRangeError struct {
+	Reason string
+}
+
+func (e *UsageRangeError) Error() string {
+	return "intervalo inválido: " + e.Reason
+}
+
+// ModelPrice é o preço de um modelo em dólares por milhão de tokens.
+type ModelPrice struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+}
+
+// defaultPrices são os preços públicos da OpenAI; OPENAI_PRICES substitui ou acrescenta modelos.
+var defaultPrices = map[string]ModelPrice{
+	"gpt-4o":        {Input: 2.50, Output: 10.00},
+	"gpt-4o-mini":   {Input: 0.15, Output: 0.60},
+	"gpt-4-turbo":   {Input: 10.00, Output: 30.00},
+	"gpt-4":         {Input: 30.00, Output: 60.00},
+	"gpt-3.5-turbo": {Input: 0.50, Output: 1.50},
+}
+
+// UsageTracker contabiliza o consumo das chamadas ao LLM no Redis, agregado por dia, por chamador e por documento.
+// O custo é guardado em micro-dólares inteiros para que os incrementos sejam atômicos e exatos.
+type UsageTracker struct {
+	prices    map[string]ModelPrice
+	retention time.Duration
+	unpriced  sync.Map
+}
+
+var Usage *UsageTracker
+
+// InitUsage carrega a tabela de preços e a retenção dos agregados.
+func InitUsage() {
+	prices := make(map[string]ModelPrice, len(defaultPrices))
+	for model, price := range defaultPrices {
+		prices[model] = price
+	}
+	for model, price := range parsePrices(config.GetEnv("OPENAI_PRICES")) {
+		prices[model] = price
+	}
+
+	Usage = &UsageTracker{
+		prices:    prices,
+		retention: durationFromEnv("USAGE_RETENTION", defaultUsageRetention),
+	}
+}
+
+// parsePrices lê entradas no formato "modelo=entrada/saida", com preços por milhão de tokens.
+func parsePrices(value string) map[string]ModelPrice {
+	prices := map[string]ModelPrice{}
+	for _, entry := range splitList(value) {
+		model, price, ok := strings.Cut(entry, "=")
+		input, output, okPrice := strings.Cut(price, "/")
+		inputValue, errInput := strconv.ParseFloat(strings.TrimSpace(input), 64)
+		outputValue, errOutput := strconv.ParseFloat(strings.TrimSpace(output), 64)
+		if !ok || !okPrice || errInput != nil || errOutput != nil || inputValue < 0 || outputValue < 0 {
+			log.Warnf("entrada inválida em OPENAI_PRICES: %s", entry)
+			continue
+		}
+		prices[strings.TrimSpace(model)] = ModelPrice{Input: inputValue, Output: outputValue}
+	}
+	return prices
+}
+
+// Price devolve o preço do modelo pelo prefixo mais longo da tabela, de modo que
+// gpt-4o-2024-08-06 use o preço de gpt-4o e não o de gpt-4.
+func (u *UsageTracker) Price(model string) (ModelPrice, bool) {
+	best := ""
+	for prefix := range u.prices {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return ModelPrice{}, false
+	}
+	return u.prices[best], true
+}
+
+// Cost calcula o custo em dólares do consumo informado pela API.
+func (u *UsageTracker) Cost(model string, usage entities.TokenUsage) float64 {
+	return float64(u.costMicros(model, usage)) / microsPerUSD
+}
+
+func (u *UsageTracker) costMicros(model string, usage entities.TokenUsage) int64 {
+	price, ok := u.Price(model)
+	if !ok {
+		if _, warned := u.unpriced.LoadOrStore(model, true); !warned {
+			log.Warnf("modelo %s sem preço configurado em OPENAI_PRICES; custo contabilizado como zero", model)
+		}
+		return 0
+	}
+	cost := float64(usage.PromptTokens)*price.Input + float64(usage.CompletionTokens)*price.Output
+	return int64(math.Round(cost)) // preço por milhão de tokens * tokens = micro-dólares
+}
+
+// usageKey devolve a chave do agregado de um período ("day" ou "month"); caller vazio é o total do período.
+func usageKey(period string, id string, caller string) string {
+	key := usageKeyPrefix + period + ":" + id
+	if caller != "" {
+		key += ":caller:" + caller
+	}
+	return key
+}
+
+// Record soma o consumo de uma chamada aos agregados do dia, do mês, do chamador e do documento do contexto.
+// Falhas no Redis são apenas registradas no log: a contabilização não interrompe o processamento.
+func (u *UsageTracker) Record(ctx context.Context, model string, usage entities.TokenUsage) {
+	if !RedisAvailable() {
+		log.Warnf("Redis indisponível, consumo de %d tokens do %s não contabilizado", usage.TotalTokens, model)
+		return
+	}
+
+	scope := scopeFromContext(ctx)
+	now := time.Now().UTC()
+	day, month := now.Format(usageDateLayout), now.Format(usageMonthLayout)
+	cost := u.costMicros(model, usage)
+
+	callersKey := usageKey("day", day, "") + ":callers"
+
+	_, err := RedisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		keys := []string{
+			usageKey("day", day, ""),
+			usageKey("day", day, scope.Caller),
+			usageKey("month", month, ""),
+			usageKey("month", month, scope.Caller),
+		}
+		if scope.DocumentID != "" {
+			documentKey := usageKeyPrefix + "document:" + scope.DocumentID
+			pipe.HSet(ctx, documentKey, "caller", scope.Caller)
+			keys = append(keys, documentKey)
+		}
+		for _, key := range keys {
+			pipe.HIncrBy(ctx, key, "calls", 1)
+			pipe.HIncrBy(ctx, key, "prompt_tokens", int64(usage.PromptTokens))
+			pipe.HIncrBy(ctx, key, "completion_tokens", int64(usage.CompletionTokens))
+			pipe.HIncrBy(ctx, key, "total_tokens", int64(usage.TotalTokens))
+			pipe.HIncrBy(ctx, key, "cost_micros", cost)
+			pipe.Expire(ctx, key, u.retention)
+		}
+		pipe.SAdd(ctx, callersKey, scope.Caller)
+		pipe.Expire(ctx, callersKey, u.retention)
+		return nil
+	})
+	if err != nil {
+		log.Error("erro ao registrar consumo no Redis: ", err)
+	}
+}
+
+// Document devolve o consumo acumulado de um documento.
+func (u *UsageTracker) Document(ctx context.Context, id string) (*entities.DocumentUsage, error) {
+	values, err := RedisClient.HGetAll(ctx, usageKeyPrefix+"document:"+id).Result()
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler consumo do documento: %w", err)
+	}
+	return &entities.DocumentUsage{DocumentID: id, Caller: values["caller"], UsageSummary: summaryFromHash(values)}, nil
+}
+
+// Report devolve o consumo por dia e chamador entre from e to (inclusive), opcionalmente de um único chamador.
+// Intervalos invertidos ou longos demais são recusados com UsageRangeError.
+func (u *UsageTracker) Report(ctx context.Context, from, to time.Time, caller string) (*entities.UsageReport, error) {
+	from, to = from.UTC().Truncate(24*time.Hour), to.UTC().Truncate(24*time.Hour)
+	if to.Before(from) {
+		return nil, &UsageRangeError{Reason: fmt.Sprintf("%s é anterior a %s", to.Format(usageDateLayout), from.Format(usageDateLayout))}
+	}
+	if days := int(to.Sub(from).Hours()/24) + 1; days > maxUsageReportDays {
+		return nil, &UsageRangeError{Reason: fmt.Sprintf("%d dias excedem o máximo de %d", days, maxUsageReportDays)}
+	}
+
+	report := &entities.UsageReport{
+		From:   from.Format(usageDateLayout),
+		To:     to.Format(usageDateLayout),
+		Caller: caller,
+		Rows:   []entities.UsageRow{},
+	}
+
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		date := day.Format(usageDateLayout)
+		callers := []string{caller}
+		if caller == "" {
+			var err error
+			callers, err = RedisClient.SMembers(ctx, usageKey("day", date, "")+":callers").Result()
+			if err != nil {
+				return nil, fmt.Errorf("erro ao ler chamadores do dia %s: %w", date, err)
+			}
+			sort.Strings(callers)
+		}
+
+		for _, name := range callers {
+			values, err := RedisClient.HGetAll(ctx, usageKey("day", date, name)).Result()
+			if err != nil {
+				return nil, fmt.Errorf("erro ao ler consumo do dia %s: %w", date, err)
+			}
+			if len(values) == 0 {
+				continue
+			}
+			row := entities.UsageRow{Date: date, Caller: name, UsageSummary: summaryFromHash(values)}
+			report.Rows = append(report.Rows, row)
+			report.Total.Add(row.UsageSummary)
+		}
+	}
+
+	return report, nil
+}
+
+func summaryFromHash(values map[string]string) entities.UsageSummary {
+	parse := func(field string) int64 {
+		value, _ := strconv.ParseInt(values[field], 10, 64)
+		return value
+	}
+	return entities.UsageSummary{
+		Calls:            parse("calls"),
+		PromptTokens:     parse("prompt_tokens"),
+		CompletionTokens: parse("completion_tokens"),
+		TotalTokens:      parse("total_tokens"),
+		CostUSD:          float64(parse("cost_micros")) / microsPerUSD,
+	}
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"github.com/gofiber/fiber/v2/log"
+	"gosmart/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	defaultJanitorInterval = 15 * time.Minute
+	defaultOrphanAge       = time.Hour
+)
+
+// WorkDirManager controla os diretórios de trabalho dos jobs (PDF enviado e imagens das páginas).
+// Cada job tem um diretório próprio sob o diretório base, removido quando o job termina,
+// a menos que WORK_DIR_RETENTION mantenha os artefatos para depuração.
+type WorkDirManager struct {
+	base      string
+	retention time.Duration
+	orphanAge time.Duration
+	interval  time.Duration
+}
+
+var WorkDirs *WorkDirManager
+
+// InitWorkDirs lê a configuração dos diretórios de trabalho do ambiente.
+func InitWorkDirs() {
+	base := config.GetEnv("WORK_DIR")
+	if base == "" {
+		base = filepath.Join(os.TempDir(), "gosmart")
+	}
+
+	WorkDirs = &WorkDirManager{
+		base:      base,
+		retention: durationFromEnv("WORK_DIR_RETENTION", 0),
+		orphanAge: durationFromEnv("WORK_DIR_ORPHAN_AGE", defaultOrphanAge),
+		interval:  durationFromEnv("WORK_DIR_JANITOR_INTERVAL", defaultJanitorInterval),
+	}
+}
+
+func durationFromEnv(key string, fallback time.Duration) time.Duration {
+	value := config.GetEnv(key)
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed < 0 {
+		log.Warnf("%s inválido (%s), usando %s", key, value, fallback)
+		return fallback
+	}
+	return parsed
+}
+
+// Create cria o diretório de trabalho do job.
+func (w *WorkDirManager) Create(id string) (string, error) {
+	dir := filepath.Join(w.base, id)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return "", fmt.Errorf("erro ao criar diretório de trabalho: %w", err)
+	}
+	return dir, nil
+}
+
+// Release remove o diretório de trabalho, exceto quando há retenção configurada;
+// nesse caso o janitor remove o diretório depois do prazo.
+func (w *WorkDirManager) Release(dir string) {
+	if w.retention > 0 {
+		return
+	}
+	w.remove(dir)
+}
+
+// Remove apaga o diretório de trabalho imediatamente, ignorando a retenção.
+func (w *WorkDirManager) Remove(dir string) {
+	w.remove(dir)
+}
+
+func (w *WorkDirManager) remove(dir string) {
+	if dir == "" || !w.contains(dir) {
+		return
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		log.Warnf("erro ao remover diretório de trabalho %s: %v", dir, err)
+	}
+}
+
+// contains garante que apenas diretórios dentro do diretório base sejam removidos.
+func (w *WorkDirManager) contains(dir string) bool {
+	rel, err := filepath.Rel(w.base, dir)
+	return err == nil && rel != "." && !strings.HasPrefix(rel, "..") && !filepath.IsAbs(rel)
+}
+
+// StartJanitor remove periodicamente diretórios órfãos, deixados por processos que caíram,
+// e diretórios cujo prazo de retenção expirou.
+func (w *WorkDirManager) StartJanitor(ctx context.Context) {
+	if w.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	w.sweep(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.sweep(ctx)
+		}
+	}
+}
+
+func (w *WorkDirManager) sweep(ctx context.Context) {
+	entries, err := os.ReadDir(w.base)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warn("erro ao listar diretórios de trabalho: ", err)
+		}
+		return
+	}
+
+	maxAge := w.orphanAge
+	if w.retention > maxAge {
+		maxAge = w.retention
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < maxAge {
+			continue
+		}
+
+		active, err := RedisClient.SIsMember(ctx, activeJobsKey, entry.Name()).Result()
+		if err != nil || active {
+			continue
+		}
+
+		w.remove(filepath.Join(w.base, entry.Name()))
+		removed++
+	}
+
+	if removed > 0 {
+		log.Infof("janitor removeu %d diretório(s) de trabalho", removed)
+	}
+}
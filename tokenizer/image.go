@@ -0,0 +1,66 @@
+package tokenizer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"math"
+	"strings"
+
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+// Custos de imagem documentados pela OpenAI: um valor base mais um valor por bloco de 512x512.
+// O gpt-4o-mini cobra as imagens com valores bem maiores.
+const (
+	imageBaseTokens     = 85
+	imageTileTokens     = 170
+	miniImageBaseTokens = 2833
+	miniImageTileTokens = 5667
+
+	// Dimensão assumida quando a imagem não pode ser decodificada.
+	fallbackImageSide = 1024
+)
+
+// ImageTokens estima os tokens de uma imagem enviada como data URI. Em detalhe "high" (padrão "auto"),
+// a imagem é reduzida para caber em 2048x2048, depois para ter o menor lado com 768px, e cobrada por bloco de 512px.
+func ImageTokens(model string, url string, detail string) int {
+	base, tile := imageBaseTokens, imageTileTokens
+	if strings.HasPrefix(model, "gpt-4o-mini") {
+		base, tile = miniImageBaseTokens, miniImageTileTokens
+	}
+	if detail == "low" {
+		return base
+	}
+
+	width, height := imageSize(url)
+	w, h := float64(width), float64(height)
+	if longest := math.Max(w, h); longest > 2048 {
+		w, h = w*2048/longest, h*2048/longest
+	}
+	if shortest := math.Min(w, h); shortest > 768 {
+		w, h = w*768/shortest, h*768/shortest
+	}
+
+	tiles := int(math.Ceil(w/512) * math.Ceil(h/512))
+	return base + tile*tiles
+}
+
+func imageSize(url string) (int, int) {
+	comma := strings.IndexByte(url, ',')
+	if !strings.HasPrefix(url, "data:") || comma < 0 {
+		return fallbackImageSide, fallbackImageSide
+	}
+
+	data, err := base64.StdEncoding.DecodeString(url[comma+1:])
+	if err != nil {
+		return fallbackImageSide, fallbackImageSide
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || config.Width == 0 || config.Height == 0 {
+		return fallbackImageSide, fallbackImageSide
+	}
+	return config.Width, config.Height
+}
@@ -0,0 +1,142 @@
+package tokenizer
+
+import (
+	"encoding/json"
+	"math"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"gosmart/entities"
+)
+
+// Encoding identifica a família de BPE usada pelo modelo.
+type Encoding string
+
+const (
+	EncodingCL100K Encoding = "cl100k_base"
+	EncodingO200K  Encoding = "o200k_base"
+)
+
+// Valores da contagem de mensagens do chat documentada pela OpenAI.
+const (
+	tokensPerMessage = 3
+	tokensPerReply   = 3
+
+	commonWordLength = 7
+)
+
+// pretokenPattern reproduz a pré-tokenização do cl100k: contrações, palavras com o espaço anterior,
+// números em grupos de até 3 dígitos, pontuação e espaços. Cada pedaço vira um ou mais tokens no BPE.
+var pretokenPattern = regexp.MustCompile(`(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+`)
+
+// modelInfo associa prefixos de nome de modelo à codificação e à janela de contexto.
+// A busca usa o prefixo mais longo, de modo que gpt-4o-mini não caia em gpt-4.
+var modelInfo = []struct {
+	prefix        string
+	encoding      Encoding
+	contextWindow int
+}{
+	{"gpt-5", EncodingO200K, 400000},
+	{"gpt-4.1", EncodingO200K, 1047576},
+	{"gpt-4o-mini", EncodingO200K, 128000},
+	{"gpt-4o", EncodingO200K, 128000},
+	{"o1", EncodingO200K, 200000},
+	{"o3", EncodingO200K, 200000},
+	{"o4", EncodingO200K, 200000},
+	{"gpt-4-turbo", EncodingCL100K, 128000},
+	{"gpt-4-1106", EncodingCL100K, 128000},
+	{"gpt-4-0125", EncodingCL100K, 128000},
+	{"gpt-4-32k", EncodingCL100K, 32768},
+	{"gpt-4", EncodingCL100K, 8192},
+	{"gpt-3.5-turbo", EncodingCL100K, 16385},
+}
+
+// DefaultContextWindow é usado para modelos fora da tabela.
+const DefaultContextWindow = 128000
+
+func lookup(model string) (Encoding, int) {
+	bestLength := -1
+	encoding, window := EncodingCL100K, DefaultContextWindow
+	for _, info := range modelInfo {
+		if strings.HasPrefix(model, info.prefix) && len(info.prefix) > bestLength {
+			bestLength = len(info.prefix)
+			encoding, window = info.encoding, info.contextWindow
+		}
+	}
+	return encoding, window
+}
+
+// EncodingFor devolve a codificação usada pelo modelo.
+func EncodingFor(model string) Encoding {
+	encoding, _ := lookup(model)
+	return encoding
+}
+
+// ContextWindow devolve o total de tokens (prompt + resposta) aceito pelo modelo.
+func ContextWindow(model string) int {
+	_, window := lookup(model)
+	return window
+}
+
+// Count estima quantos tokens o texto ocupa no modelo. A estimativa é feita sobre a pré-tokenização
+// real do BPE, mas sem o vocabulário: palavras longas e com acentos são divididas pelo tamanho médio
+// dos tokens, o que tende a superestimar levemente. Não é a contagem exata: sequências de pontuação
+// contam como um token e podem ocupar mais, então quem usa a estimativa como orçamento deve somar uma folga.
+func Count(model string, text string) int {
+	charsPerToken := 4.0
+	accentedCharsPerToken := 2.5
+	if EncodingFor(model) == EncodingO200K {
+		// O vocabulário maior do o200k cobre melhor o português
+		accentedCharsPerToken = 3.0
+	}
+
+	total := 0
+	for _, piece := range pretokenPattern.FindAllString(text, -1) {
+		trimmed := strings.TrimLeft(piece, " ")
+		runes := utf8.RuneCountInString(trimmed)
+		switch {
+		case runes == 0 || strings.TrimSpace(piece) == "":
+			total++
+		case len(trimmed) == runes && runes <= commonWordLength:
+			// Palavras curtas sem acento costumam ser um único token
+			total++
+		case len(trimmed) == runes:
+			total += int(math.Ceil(float64(runes) / charsPerToken))
+		default:
+			total += int(math.Max(1, math.Ceil(float64(runes)/accentedCharsPerToken)))
+		}
+	}
+	return total
+}
+
+// CountRequest estima os tokens de prompt de uma requisição de chat: mensagens, imagens,
+// ferramentas e esquema de resposta, com o custo fixo por mensagem.
+func CountRequest(request entities.ChatCompletionRequest) int {
+	total := tokensPerReply
+	for _, message := range request.Messages {
+		total += tokensPerMessage + Count(request.Model, message.Role)
+		if len(message.Parts) == 0 {
+			total += Count(request.Model, message.Content)
+			continue
+		}
+		for _, part := range message.Parts {
+			switch {
+			case part.ImageURL != nil:
+				total += ImageTokens(request.Model, part.ImageURL.URL, part.ImageURL.Detail)
+			default:
+				total += Count(request.Model, part.Text)
+			}
+		}
+	}
+
+	if len(request.Tools) > 0 {
+		definition, _ := json.Marshal(request.Tools)
+		total += Count(request.Model, string(definition))
+	}
+	if request.ResponseFormat != nil {
+		definition, _ := json.Marshal(request.ResponseFormat)
+		total += Count(request.Model, string(definition))
+	}
+	return total
+}
@@ -0,0 +1,43 @@
+package tokenizer
+
+import "testing"
+
+// As contagens reais são as do tiktoken, iguais no cl100k_base e no o200k_base para estes textos.
+func TestCountIsNotBelowKnownCounts(t *testing.T) {
+	tests := []struct {
+		text   string
+		tokens int
+	}{
+		{text: "hello world", tokens: 2},
+		{text: "Hello, world!", tokens: 4},
+		{text: "The quick brown fox jumps over the lazy dog.", tokens: 10},
+	}
+
+	for _, model := range []string{"gpt-4", "gpt-4o"} {
+		for _, tt := range tests {
+			got := Count(model, tt.text)
+			if got < tt.tokens || got > 2*tt.tokens {
+				t.Errorf("Count(%s, %q) = %d, esperado entre %d e %d", model, tt.text, got, tt.tokens, 2*tt.tokens)
+			}
+		}
+	}
+}
+
+func TestEncodingFor(t *testing.T) {
+	tests := []struct {
+		model string
+		want  Encoding
+	}{
+		{model: "gpt-4o-mini", want: EncodingO200K},
+		{model: "gpt-4o-2024-08-06", want: EncodingO200K},
+		{model: "gpt-4-turbo", want: EncodingCL100K},
+		{model: "gpt-3.5-turbo", want: EncodingCL100K},
+		{model: "modelo-desconhecido", want: EncodingCL100K},
+	}
+
+	for _, tt := range tests {
+		if got := EncodingFor(tt.model); got != tt.want {
+			t.Errorf("EncodingFor(%s) = %s, esperado %s", tt.model, got, tt.want)
+		}
+	}
+}